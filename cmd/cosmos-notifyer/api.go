@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"nysa-network/pkg/notifyer"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiSilencesPath     = "/api/v1/silences"
+	apiAlertmanagerPath = "/api/v1/alertmanager"
+	apiTenderdutyPath   = "/api/v1/tenderduty"
+
+	// apiMaxBody is the maximum size of a request body
+	apiMaxBody = 1 << 20
+)
+
+// startAPI serve the local API until ctx is done
+func (s *service) startAPI(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiSilencesPath, s.handleSilences)
+	mux.HandleFunc(apiSilencesPath+"/", s.handleSilence)
+	mux.HandleFunc(apiAlertmanagerPath, s.handleAlertmanager)
+	mux.HandleFunc(apiTenderdutyPath, s.handleTenderduty)
+
+	srv := &http.Server{
+		Addr:              s.cfg.API.Listen,
+		Handler:           s.authenticate(limitBody(mux)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	if s.cfg.API.Token == "" && !loopback(s.cfg.API.Listen) {
+		logrus.WithField("listen", s.cfg.API.Listen).Warn("api: no token set, anyone reaching the api can send alerts and silence them")
+	}
+	logrus.WithField("listen", s.cfg.API.Listen).Info("api listening")
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// authenticate require the bearer token of the config, when set
+func (s *service) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.API.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.API.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopback return true when addr only listen on the loopback interface
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// limitBody reject the request bodies larger than apiMaxBody
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, apiMaxBody)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyStatus return the status of a request body error
+func bodyStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// handleSilences list (GET) or create (POST) silences
+func (s *service) handleSilences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.notify.Silences())
+	case http.MethodPost:
+		silence := notifyer.Silence{}
+		if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+			writeError(w, bodyStatus(err), err)
+			return
+		}
+		silence, err := s.notify.AddSilence(silence)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, silence)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSilence expire (DELETE) a silence
+func (s *service) handleSilence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, apiSilencesPath+"/")
+	if err := s.notify.ExpireSilence(id); err != nil {
+		if errors.IsNotFound(err) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAlertmanager receive the alerts of an Alertmanager webhook receiver
+func (s *service) handleAlertmanager(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload := notifyer.AlertmanagerWebhook{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, bodyStatus(err), err)
+		return
+	}
+	if err := s.notify.ReceiveAlertmanager(payload); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTenderduty receive a tenderduty-style alert or a list of them
+func (s *service) handleTenderduty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, bodyStatus(err), err)
+		return
+	}
+
+	alerts := []notifyer.TenderdutyAlert{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(body, &alerts)
+	} else {
+		alert := notifyer.TenderdutyAlert{}
+		err = json.Unmarshal(body, &alert)
+		alerts = append(alerts, alert)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.notify.ReceiveTenderduty(alerts...); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Error("failed to write api response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:9090": true,
+		"localhost:9090": true,
+		"[::1]:9090":     true,
+		":9090":          false,
+		"0.0.0.0:9090":   false,
+		"10.0.0.2:9090":  false,
+		"invalid":        false,
+	}
+	for addr, want := range tests {
+		if got := loopback(addr); got != want {
+			t.Errorf("loopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestAPIMaxBody(t *testing.T) {
+	s := &service{cfg: &Config{}}
+	h := limitBody(http.HandlerFunc(s.handleTenderduty))
+
+	body := bytes.Repeat([]byte(" "), apiMaxBody+1)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiTenderdutyPath, bytes.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
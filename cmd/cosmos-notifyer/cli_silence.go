@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"nysa-network/pkg/notifyer"
+
+	"github.com/juju/errors"
+	"github.com/urfave/cli/v2"
+)
+
+var silenceFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "api",
+		Value: "http://127.0.0.1:9464",
+		Usage: "cosmos-notifyer local API `URL`",
+	},
+	&cli.StringFlag{
+		Name:    "token",
+		EnvVars: []string{"CN_API_TOKEN"},
+		Usage:   "bearer `TOKEN` of the API",
+	},
+}
+
+func silenceCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "silence",
+		Usage: "manage silences of a running cosmos-notifyer",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "add",
+				Usage: "mute notifications matching chains and kinds",
+				Flags: append([]cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "chain",
+						Usage: "chain `NAME` to mute, every chain when empty",
+					},
+					&cli.StringSliceFlag{
+						Name:  "kind",
+						Usage: "event `KIND` to mute (jailed, missed-blocks...), every kind when empty",
+					},
+					&cli.TimestampFlag{
+						Name:   "starts-at",
+						Layout: time.RFC3339,
+						Usage:  "start `TIME` (RFC3339), now when empty",
+					},
+					&cli.TimestampFlag{
+						Name:   "ends-at",
+						Layout: time.RFC3339,
+						Usage:  "end `TIME` (RFC3339)",
+					},
+					&cli.DurationFlag{
+						Name:  "duration",
+						Value: time.Hour,
+						Usage: "`DURATION` of the silence when ends-at is empty",
+					},
+					&cli.StringFlag{
+						Name:  "comment",
+						Usage: "reason of the silence",
+					},
+				}, silenceFlags...),
+				Action: silenceAdd,
+			},
+			{
+				Name:   "list",
+				Usage:  "list silences not ended yet",
+				Flags:  silenceFlags,
+				Action: silenceList,
+			},
+			{
+				Name:      "expire",
+				Usage:     "end a silence now",
+				ArgsUsage: "ID",
+				Flags:     silenceFlags,
+				Action:    silenceExpire,
+			},
+		},
+	}
+}
+
+func silenceAdd(c *cli.Context) error {
+	silence := notifyer.Silence{
+		Chains:  c.StringSlice("chain"),
+		Comment: c.String("comment"),
+	}
+	for _, kind := range c.StringSlice("kind") {
+		silence.Kinds = append(silence.Kinds, notifyer.EventKind(kind))
+	}
+
+	silence.StartsAt = time.Now()
+	if t := c.Timestamp("starts-at"); t != nil {
+		silence.StartsAt = *t
+	}
+	silence.EndsAt = silence.StartsAt.Add(c.Duration("duration"))
+	if t := c.Timestamp("ends-at"); t != nil {
+		silence.EndsAt = *t
+	}
+
+	body, err := json.Marshal(silence)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := apiRequest(c, http.MethodPost, "", body, &silence); err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Println(silence.ID)
+	return nil
+}
+
+func silenceList(c *cli.Context) error {
+	silences := []notifyer.Silence{}
+	if err := apiRequest(c, http.MethodGet, "", nil, &silences); err != nil {
+		return errors.Trace(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCHAINS\tKINDS\tSTARTS AT\tENDS AT\tCOMMENT")
+	for _, silence := range silences {
+		kinds := make([]string, 0, len(silence.Kinds))
+		for _, kind := range silence.Kinds {
+			kinds = append(kinds, string(kind))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			silence.ID,
+			orAll(strings.Join(silence.Chains, ",")),
+			orAll(strings.Join(kinds, ",")),
+			silence.StartsAt.Local().Format(time.RFC3339),
+			silence.EndsAt.Local().Format(time.RFC3339),
+			silence.Comment,
+		)
+	}
+	return w.Flush()
+}
+
+func silenceExpire(c *cli.Context) error {
+	id := c.Args().First()
+	if id == "" {
+		return errors.New("missing silence ID")
+	}
+	return errors.Trace(apiRequest(c, http.MethodDelete, "/"+id, nil, nil))
+}
+
+// apiRequest call the silences endpoint of the local API and decode the
+// response into out when not nil
+func apiRequest(c *cli.Context, method string, path string, body []byte, out interface{}) error {
+	url := strings.TrimSuffix(c.String("api"), "/") + apiSilencesPath + path
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := c.String("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := struct {
+			Error string `json:"error"`
+		}{}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return errors.Errorf("%s %s: %s %s", method, url, resp.Status, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.Trace(json.NewDecoder(resp.Body).Decode(out))
+}
+
+func orAll(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
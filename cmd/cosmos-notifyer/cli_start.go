@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,9 +17,32 @@ import (
 )
 
 func (s *service) Start(cctx *cli.Context) error {
-	s.notify = notifyer.NewClient(notifyer.Config{
-		DiscordWebhook: s.cfg.Notifications.Discord.Webhook,
-	})
+	notify, err := notifyer.NewClient(s.cfg.GetNotifyerConfig())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.notify = notify
+	defer s.notify.Close()
+
+	s.stats = map[string]*chainStats{}
+	for _, chain := range s.cfg.Chains {
+		s.stats[chain.Name] = newChainStats()
+	}
+
+	if s.cfg.Digest != nil {
+		if _, err := s.cfg.Digest.next(time.Now()); err != nil {
+			return errors.Trace(err)
+		}
+		go s.startDigest(cctx.Context)
+	}
+
+	if s.cfg.API.Listen != "" {
+		go func() {
+			if err := s.startAPI(cctx.Context); err != nil {
+				logrus.WithError(err).Error("api stopped")
+			}
+		}()
+	}
 
 	wg := sync.WaitGroup{}
 
@@ -28,7 +52,8 @@ func (s *service) Start(cctx *cli.Context) error {
 		go func(chain Chain) {
 			defer wg.Done()
 
-			activeRPC := true
+			// resume the conditions left open before a restart
+			activeRPC := !s.notify.Unresolved(chain.Name, notifyer.KindRPCDown)
 
 			for {
 				rpcs := cosmosblocks.CheckRPCs(chain.RPC)
@@ -36,8 +61,15 @@ func (s *service) Start(cctx *cli.Context) error {
 
 				if rpc == nil {
 					if activeRPC {
-						s.notify.Alert(notifyer.AlertMsg{
-							Msg: fmt.Sprintf("[%s] No valid RPC (0/%d)", chain.Name, len(rpcs)),
+						s.alert(notifyer.AlertMsg{
+							Event: notifyer.Event{
+								Chain:     chain.Name,
+								Kind:      notifyer.KindRPCDown,
+								Validator: chain.ValidatorAddr,
+								Fields: map[string]string{
+									"rpcs": fmt.Sprintf("0/%d", len(rpcs)),
+								},
+							},
 						})
 					}
 					activeRPC = false
@@ -45,7 +77,11 @@ func (s *service) Start(cctx *cli.Context) error {
 					continue
 				} else if !activeRPC && rpc != nil {
 					s.notify.Recover(notifyer.RecoverMsg{
-						Msg: fmt.Sprintf("[%s] RPCs are back up ! ", chain.Name),
+						Event: notifyer.Event{
+							Chain:     chain.Name,
+							Kind:      notifyer.KindRPCDown,
+							Validator: chain.ValidatorAddr,
+						},
 					})
 					activeRPC = true
 				}
@@ -107,10 +143,16 @@ func (s *service) blockHandler(ctx context.Context, c *cosmosblocks.Client, chai
 		missedBlocks      int64     = 0
 		missedBlocksAlert int64     = missedBlocksAlertInit
 
-		isJailed bool = false
-		isBonded bool = true
+		// resume the conditions left open before a restart or a reconnection
+		isJailed bool = s.notify.Unresolved(chain.Name, notifyer.KindJailed)
+		isBonded bool = !s.notify.Unresolved(chain.Name, notifyer.KindInactive)
 	)
 
+	if s.notify.Unresolved(chain.Name, notifyer.KindMissedBlocks) {
+		// the next signed block recover it
+		missedBlocks = missedBlocksAlertInit
+	}
+
 START:
 
 	validator, err := c.QueryValidator(chain.ValidatorAddr)
@@ -121,9 +163,13 @@ START:
 	if validator.Validator.IsJailed() {
 		if !isJailed {
 			isJailed = true
-			s.notify.Alert(notifyer.AlertMsg{
-				Msg: fmt.Sprintf("[%s] %s is jailed",
-					chain.Name, validator.Validator.GetMoniker()),
+			s.alert(notifyer.AlertMsg{
+				Event: notifyer.Event{
+					Chain:     chain.Name,
+					Kind:      notifyer.KindJailed,
+					Validator: chain.ValidatorAddr,
+					Moniker:   validator.Validator.GetMoniker(),
+				},
 			})
 		}
 		time.Sleep(time.Second * 30)
@@ -131,17 +177,25 @@ START:
 	} else if !validator.Validator.IsJailed() && isJailed {
 		isJailed = false
 		s.notify.Recover(notifyer.RecoverMsg{
-			Msg: fmt.Sprintf("[%s] %s is un-jailed",
-				chain.Name, validator.Validator.GetMoniker()),
+			Event: notifyer.Event{
+				Chain:     chain.Name,
+				Kind:      notifyer.KindJailed,
+				Validator: chain.ValidatorAddr,
+				Moniker:   validator.Validator.GetMoniker(),
+			},
 		})
 	}
 
 	if !validator.Validator.IsBonded() {
 		if isBonded {
 			isBonded = false
-			s.notify.Alert(notifyer.AlertMsg{
-				Msg: fmt.Sprintf("[%s] validator: %s is not in the active set",
-					chain.Name, validator.Validator.GetMoniker()),
+			s.alert(notifyer.AlertMsg{
+				Event: notifyer.Event{
+					Chain:     chain.Name,
+					Kind:      notifyer.KindInactive,
+					Validator: chain.ValidatorAddr,
+					Moniker:   validator.Validator.GetMoniker(),
+				},
 			})
 		}
 		time.Sleep(time.Second * 30)
@@ -149,8 +203,12 @@ START:
 	} else if validator.Validator.IsBonded() && !isBonded {
 		isBonded = true
 		s.notify.Recover(notifyer.RecoverMsg{
-			Msg: fmt.Sprintf("[%s] validator: %s is back in the active set",
-				chain.Name, validator.Validator.GetMoniker()),
+			Event: notifyer.Event{
+				Chain:     chain.Name,
+				Kind:      notifyer.KindInactive,
+				Validator: chain.ValidatorAddr,
+				Moniker:   validator.Validator.GetMoniker(),
+			},
 		})
 	}
 
@@ -159,6 +217,8 @@ START:
 		return errors.Errorf("failed to parse validator address: %s", chain.ValidatorAddr)
 	}
 
+	go s.updateRank(c, chain, validatorAddr)
+
 	go func(ctx context.Context) {
 		for {
 			select {
@@ -193,16 +253,30 @@ START:
 			}
 			latestBlockHeight = block.GetHeight()
 
+			if latestBlockHeight%digestRankInterval == 0 {
+				go s.updateRank(c, chain, validatorAddr)
+			}
+
 			// Check validator signed block
-			if !block.IsValidatorSigned(validatorAddr) {
+			signed := block.IsValidatorSigned(validatorAddr)
+			s.stats[chain.Name].block(signed)
+			if !signed {
 				l.Error("Validator didn't signed block")
 				missedBlocks += 1
 
 				if missedBlocks >= missedBlocksAlert {
 					missedBlocksAlert += 150
-					err := s.notify.Alert(notifyer.AlertMsg{
-						Msg: fmt.Sprintf("[%s] %s Not signing blocs... %d blocks",
-							chain.Name, validator.Validator.GetMoniker(), missedBlocks),
+					err := s.alert(notifyer.AlertMsg{
+						Event: notifyer.Event{
+							Chain:     chain.Name,
+							Kind:      notifyer.KindMissedBlocks,
+							Validator: chain.ValidatorAddr,
+							Moniker:   validator.Validator.GetMoniker(),
+							Height:    block.GetHeight(),
+							Fields: map[string]string{
+								"missed_blocks": strconv.FormatInt(missedBlocks, 10),
+							},
+						},
 					})
 					if err != nil {
 						l.WithError(err).WithFields(logrus.Fields{
@@ -211,10 +285,18 @@ START:
 					}
 				}
 			} else {
-				if missedBlocks > missedBlocksAlertInit {
+				if missedBlocks >= missedBlocksAlertInit {
 					s.notify.Recover(notifyer.RecoverMsg{
-						Msg: fmt.Sprintf("[%s] %s Signing block again, missed blocks: %d",
-							chain.Name, validator.Validator.GetMoniker(), missedBlocks),
+						Event: notifyer.Event{
+							Chain:     chain.Name,
+							Kind:      notifyer.KindMissedBlocks,
+							Validator: chain.ValidatorAddr,
+							Moniker:   validator.Validator.GetMoniker(),
+							Height:    block.GetHeight(),
+							Fields: map[string]string{
+								"missed_blocks": strconv.FormatInt(missedBlocks, 10),
+							},
+						},
 					})
 				}
 				missedBlocks = 0
@@ -225,8 +307,19 @@ START:
 			for _, msg := range block.GetMsgDelegate() {
 				if chain.ValidatorAddr == msg.ValoperAddr {
 					amount := msg.GetAmount() / float64(chain.GetTokenCoefficient())
+					s.stats[chain.Name].delegation(amount, block.GetHeight())
 					if amount > chain.Notification.MinimumDelegation {
 						err := s.notify.Delegation(notifyer.DelegationMsg{
+							Event: notifyer.Event{
+								Chain:     chain.Name,
+								Validator: chain.ValidatorAddr,
+								Moniker:   validator.Validator.GetMoniker(),
+								Height:    block.GetHeight(),
+								Fields: map[string]string{
+									"delegator": msg.Delegator,
+									"tx_hash":   msg.TxHash,
+								},
+							},
 							Amount: amount,
 							Token:  chain.Token.Label,
 						})
@@ -245,8 +338,19 @@ START:
 			for _, msg := range block.GetMsgUndelegate() {
 				if chain.ValidatorAddr == msg.ValoperAddr {
 					amount := msg.GetAmount() / float64(chain.GetTokenCoefficient())
+					s.stats[chain.Name].undelegation(amount, block.GetHeight())
 					if amount > chain.Notification.MinimumDelegation {
 						err := s.notify.UnDelegation(notifyer.UnDelegationMsg{
+							Event: notifyer.Event{
+								Chain:     chain.Name,
+								Validator: chain.ValidatorAddr,
+								Moniker:   validator.Validator.GetMoniker(),
+								Height:    block.GetHeight(),
+								Fields: map[string]string{
+									"delegator": msg.Delegator,
+									"tx_hash":   msg.TxHash,
+								},
+							},
 							Amount: amount,
 							Token:  chain.Token.Label,
 						})
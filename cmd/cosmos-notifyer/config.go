@@ -1,16 +1,49 @@
 package main
 
-import "github.com/sirupsen/logrus"
+import (
+	"sort"
+	"time"
+
+	"nysa-network/pkg/notifyer"
+
+	"github.com/sirupsen/logrus"
+)
 
 type Config struct {
 	LogLevel string `yaml:"log_level"`
 
+	API struct {
+		Listen string `yaml:"listen"`
+		// Token is the bearer token required by the API, when set
+		Token string `yaml:"token"`
+	} `yaml:"api"`
+
 	Chains []Chain `yaml:"chains"`
 
+	Digest *DigestConfig `yaml:"digest"`
+
 	Notifications struct {
-		Discord *struct {
-			Webhook string `yaml:"webhook"`
-		} `yaml:"discord"`
+		// Backends are the legacy sections keyed by backend type (discord,
+		// slack...), each one is a destination named after its type
+		Backends map[string]notifyer.DestinationConfig `yaml:",inline"`
+
+		Destinations []notifyer.DestinationConfig `yaml:"destinations"`
+
+		Routes []notifyer.Route `yaml:"routes"`
+
+		Escalations []notifyer.Escalation `yaml:"escalations"`
+
+		Templates notifyer.Templates `yaml:"templates"`
+
+		Dedup notifyer.DedupConfig `yaml:"dedup"`
+
+		Silences []notifyer.Silence `yaml:"silences"`
+
+		Database string `yaml:"database"`
+
+		Outbox struct {
+			MaxAge time.Duration `yaml:"max_age"`
+		} `yaml:"outbox"`
 	} `yaml:"notifications"`
 }
 
@@ -27,6 +60,8 @@ type Chain struct {
 	Notification struct {
 		MinimumDelegation float64 `yaml:"minimum_delegation"`
 	} `yaml:"notification"`
+
+	Explorer notifyer.Explorer `yaml:"explorer"`
 }
 
 func (cfg Config) GetLogLevel() logrus.Level {
@@ -42,6 +77,40 @@ func (cfg Config) GetLogLevel() logrus.Level {
 	return logrus.InfoLevel
 }
 
+// GetNotifyerConfig convert the notifications section into a notifyer.Config
+func (cfg Config) GetNotifyerConfig() notifyer.Config {
+	c := notifyer.Config{
+		Routes:      cfg.Notifications.Routes,
+		Escalations: cfg.Notifications.Escalations,
+		Templates:   cfg.Notifications.Templates,
+		Dedup:       cfg.Notifications.Dedup,
+		Silences:    cfg.Notifications.Silences,
+		Explorers:   map[string]notifyer.Explorer{},
+
+		DatabasePath: cfg.Notifications.Database,
+		OutboxMaxAge: cfg.Notifications.Outbox.MaxAge,
+	}
+
+	for _, chain := range cfg.Chains {
+		c.Explorers[chain.Name] = chain.Explorer
+	}
+
+	types := make([]string, 0, len(cfg.Notifications.Backends))
+	for typ := range cfg.Notifications.Backends {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	destinations := make([]notifyer.DestinationConfig, 0, len(types)+len(cfg.Notifications.Destinations))
+	for _, typ := range types {
+		d := cfg.Notifications.Backends[typ]
+		d.Name, d.Type = typ, typ
+		destinations = append(destinations, d)
+	}
+	c.Destinations = append(destinations, cfg.Notifications.Destinations...)
+	return c
+}
+
 func (c Chain) GetTokenCoefficient() int {
 	if c.Token.Coefficient == 0 {
 		return 1000000
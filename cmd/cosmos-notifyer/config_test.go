@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"nysa-network/pkg/notifyer"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGetNotifyerConfig(t *testing.T) {
+	cfg := Config{}
+	err := yaml.Unmarshal([]byte(`
+notifications:
+  slack:
+    webhook: "https://hooks.slack.com/services/xxx"
+  discord:
+    webhook: "https://discord.com/api/webhooks/xxx"
+    kinds: [jailed]
+  destinations:
+    - name: oncall
+      type: ntfy
+      url: "https://ntfy.sh/oncall"
+  dedup:
+    alert_after: 1m
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.GetNotifyerConfig()
+	want := []string{"discord:discord", "slack:slack", "oncall:ntfy"}
+	got := []string{}
+	for _, d := range c.Destinations {
+		got = append(got, d.Name+":"+d.Type)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("destinations %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("destinations %v, want %v", got, want)
+		}
+	}
+	if len(c.Destinations[0].Kinds) != 1 || c.Dedup.AlertAfter == 0 {
+		t.Fatalf("settings not decoded: %+v", c)
+	}
+
+	settings := notifyer.DiscordClient{}
+	if err := c.Destinations[0].Decode(&settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Webhook != "https://discord.com/api/webhooks/xxx" {
+		t.Fatalf("webhook = %q", settings.Webhook)
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	data, err := os.ReadFile("../../config.example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.GetNotifyerConfig()
+	c.DatabasePath = ""
+	client, err := notifyer.NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+}
+
+func TestInvalidBackendSection(t *testing.T) {
+	for _, section := range []string{
+		"discord:\n    webhok: https://discord.com/api/webhooks/xxx",
+		"telegram:\n    token: \"123:xxx\"",
+		"slack: {}",
+	} {
+		cfg := Config{}
+		if err := yaml.Unmarshal([]byte("notifications:\n  "+section+"\n"), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if c, err := notifyer.NewClient(cfg.GetNotifyerConfig()); err == nil {
+			c.Close()
+			t.Errorf("%q accepted", section)
+		}
+	}
+}
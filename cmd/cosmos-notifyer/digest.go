@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	// embed the timezone database, the docker image does not ship it
+	_ "time/tzdata"
+
+	"nysa-network/pkg/cosmosblocks"
+	"nysa-network/pkg/notifyer"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// digestLargest is the number of largest (un)delegations in a digest
+	digestLargest = 3
+	// digestRankInterval is the number of blocks between two rank queries
+	digestRankInterval = 500
+)
+
+// DigestConfig schedule the periodic digest of every chain
+type DigestConfig struct {
+	// Schedule is "daily" or "weekly"
+	Schedule string `yaml:"schedule"`
+	// Time is the time of the day, as "15:04"
+	Time string `yaml:"time"`
+	// Weekday of a weekly digest, as "monday"
+	Weekday string `yaml:"weekday"`
+	// Timezone of Time, as "Europe/Paris" (default UTC)
+	Timezone string `yaml:"timezone"`
+}
+
+func (cfg DigestConfig) location() (*time.Location, error) {
+	if cfg.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, errors.Annotate(err, "digest: timezone")
+	}
+	return loc, nil
+}
+
+// next return the first digest time after now
+func (cfg DigestConfig) next(now time.Time) (time.Time, error) {
+	loc, err := cfg.location()
+	if err != nil {
+		return time.Time{}, errors.Trace(err)
+	}
+
+	at, err := time.Parse("15:04", cfg.Time)
+	if err != nil {
+		return time.Time{}, errors.Annotate(err, "digest: time")
+	}
+
+	now = now.In(loc)
+	t := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+
+	switch cfg.Schedule {
+	case "daily":
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+	case "weekly":
+		weekday, err := parseWeekday(cfg.Weekday)
+		if err != nil {
+			return time.Time{}, errors.Trace(err)
+		}
+		for t.Weekday() != weekday || !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+	default:
+		return time.Time{}, errors.Errorf("digest: unknown schedule: %q", cfg.Schedule)
+	}
+	return t, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return 0, errors.Errorf("digest: unknown weekday: %q", s)
+}
+
+type digestDelegation struct {
+	Amount float64
+	Height int64
+}
+
+// digestStats are the statistics of a chain since its latest digest
+type digestStats struct {
+	since         time.Time
+	signed        int64
+	missed        int64
+	alerts        int
+	delegated     float64
+	undelegated   float64
+	delegations   []digestDelegation
+	undelegations []digestDelegation
+
+	rank *cosmosblocks.ValidatorRank
+}
+
+// chainStats collect the digestStats of a chain
+type chainStats struct {
+	mu sync.Mutex
+
+	digestStats
+}
+
+func newChainStats() *chainStats {
+	return &chainStats{
+		digestStats: digestStats{since: time.Now()},
+	}
+}
+
+func (st *chainStats) block(signed bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if signed {
+		st.signed++
+	} else {
+		st.missed++
+	}
+}
+
+func (st *chainStats) alert() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.alerts++
+}
+
+func (st *chainStats) delegation(amount float64, height int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.delegated += amount
+	st.delegations = largest(st.delegations, digestDelegation{amount, height})
+}
+
+func (st *chainStats) undelegation(amount float64, height int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.undelegated += amount
+	st.undelegations = largest(st.undelegations, digestDelegation{amount, height})
+}
+
+func (st *chainStats) setRank(rank *cosmosblocks.ValidatorRank) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.rank = rank
+}
+
+// reset return the statistics and start a new period, the rank is kept
+func (st *chainStats) reset() digestStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ret := st.digestStats
+	st.digestStats = digestStats{since: time.Now(), rank: st.rank}
+	return ret
+}
+
+// largest insert d into list, sorted and truncated to digestLargest
+func largest(list []digestDelegation, d digestDelegation) []digestDelegation {
+	list = append(list, d)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Amount > list[j].Amount
+	})
+	if len(list) > digestLargest {
+		list = list[:digestLargest]
+	}
+	return list
+}
+
+// alert send msg and count it in the chain digest
+func (s *service) alert(msg notifyer.AlertMsg) error {
+	if st, ok := s.stats[msg.Chain]; ok {
+		st.alert()
+	}
+	return s.notify.Alert(msg)
+}
+
+// updateRank refresh the rank of the validator in the chain digest
+func (s *service) updateRank(c *cosmosblocks.Client, chain Chain, valconsAddr []byte) {
+	rank, err := c.QueryValidatorRank(valconsAddr)
+	if err != nil {
+		logrus.WithError(err).WithField("chain", chain.Name).Warn("failed to query validator rank")
+		return
+	}
+	s.stats[chain.Name].setRank(rank)
+}
+
+// startDigest send the digest of every chain on schedule until ctx is done
+func (s *service) startDigest(ctx context.Context) {
+	for {
+		next, err := s.cfg.Digest.next(time.Now())
+		if err != nil {
+			logrus.WithError(err).Error()
+			return
+		}
+		logrus.WithField("at", next.Format(time.RFC3339)).Debug("next digest")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		for _, chain := range s.cfg.Chains {
+			s.notify.Info(s.digest(chain, s.stats[chain.Name].reset()))
+		}
+	}
+}
+
+// digest render the statistics of a chain
+func (s *service) digest(chain Chain, st digestStats) notifyer.InfoMsg {
+	token := chain.Token.Label
+	loc, err := s.cfg.Digest.location()
+	if err != nil {
+		loc = time.UTC
+	}
+	lines := []string{
+		fmt.Sprintf("Period: %s - %s",
+			st.since.In(loc).Format("02 Jan 15:04"), time.Now().In(loc).Format("02 Jan 15:04 MST")),
+	}
+
+	// no block seen, e.g. every RPC was down
+	uptime := "n/a"
+	if total := st.signed + st.missed; total > 0 {
+		uptime = fmt.Sprintf("%.2f%%", float64(st.signed)/float64(total)*100)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Blocks: %d signed, %d missed, uptime %s", st.signed, st.missed, uptime),
+		fmt.Sprintf("Alerts fired: %d", st.alerts),
+		fmt.Sprintf("Delegations: +%.2f %s, undelegations: -%.2f %s, net flow: %+.2f %s",
+			st.delegated, token, st.undelegated, token, st.delegated-st.undelegated, token),
+	)
+	if len(st.delegations) > 0 {
+		lines = append(lines, "Largest delegations: "+formatDelegations(st.delegations, token))
+	}
+	if len(st.undelegations) > 0 {
+		lines = append(lines, "Largest undelegations: "+formatDelegations(st.undelegations, token))
+	}
+
+	if st.rank != nil && st.rank.Rank > 0 {
+		power := 0.0
+		if st.rank.TotalVotingPower > 0 {
+			power = float64(st.rank.VotingPower) / float64(st.rank.TotalVotingPower) * 100
+		}
+		lines = append(lines, fmt.Sprintf("Rank: #%d/%d, voting power %d (%.2f%%)",
+			st.rank.Rank, st.rank.Validators, st.rank.VotingPower, power))
+	} else if st.rank != nil {
+		lines = append(lines, "Rank: not in the active set")
+	}
+
+	return notifyer.InfoMsg{
+		Event: notifyer.Event{
+			Chain:     chain.Name,
+			Kind:      notifyer.KindDigest,
+			Validator: chain.ValidatorAddr,
+		},
+		Title: fmt.Sprintf("%s %s digest", chain.Name, s.cfg.Digest.Schedule),
+		Msg:   strings.Join(lines, "\n"),
+	}
+}
+
+func formatDelegations(list []digestDelegation, token string) string {
+	ret := make([]string, 0, len(list))
+	for _, d := range list {
+		ret = append(ret, fmt.Sprintf("%.2f %s (#%d)", d.Amount, token, d.Height))
+	}
+	return strings.Join(ret, ", ")
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nysa-network/pkg/cosmosblocks"
+)
+
+func TestDigestNext(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-05-01 is a wednesday
+	wednesday := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		cfg  DigestConfig
+		now  time.Time
+		want time.Time
+	}{
+		{
+			"daily later today",
+			DigestConfig{Schedule: "daily", Time: "18:00"},
+			wednesday,
+			time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			"daily tomorrow",
+			DigestConfig{Schedule: "daily", Time: "09:00"},
+			wednesday,
+			time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			"daily right now is tomorrow",
+			DigestConfig{Schedule: "daily", Time: "10:00"},
+			wednesday,
+			time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			"daily in a timezone",
+			DigestConfig{Schedule: "daily", Time: "09:00", Timezone: "Europe/Paris"},
+			wednesday,
+			time.Date(2024, 5, 2, 9, 0, 0, 0, paris),
+		},
+		{
+			"daily across the timezone day",
+			DigestConfig{Schedule: "daily", Time: "01:00", Timezone: "Europe/Paris"},
+			time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC),
+			time.Date(2024, 5, 3, 1, 0, 0, 0, paris),
+		},
+		{
+			"daily across a DST change",
+			DigestConfig{Schedule: "daily", Time: "09:00", Timezone: "Europe/Paris"},
+			time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly later this week",
+			DigestConfig{Schedule: "weekly", Time: "09:00", Weekday: "Friday"},
+			wednesday,
+			time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly later today",
+			DigestConfig{Schedule: "weekly", Time: "18:00", Weekday: "wednesday"},
+			wednesday,
+			time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly earlier today is next week",
+			DigestConfig{Schedule: "weekly", Time: "09:00", Weekday: "wednesday"},
+			wednesday,
+			time.Date(2024, 5, 8, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly in a timezone",
+			DigestConfig{Schedule: "weekly", Time: "08:00", Weekday: "monday", Timezone: "Europe/Paris"},
+			wednesday,
+			time.Date(2024, 5, 6, 8, 0, 0, 0, paris),
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.cfg.next(tt.now)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: next = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDigestNextErrors(t *testing.T) {
+	tests := []DigestConfig{
+		{Schedule: "monthly", Time: "09:00"},
+		{Schedule: "daily", Time: "9am"},
+		{Schedule: "weekly", Time: "09:00", Weekday: "someday"},
+		{Schedule: "daily", Time: "09:00", Timezone: "Mars/Olympus"},
+	}
+	for _, cfg := range tests {
+		if _, err := cfg.next(time.Now()); err == nil {
+			t.Errorf("%+v: no error", cfg)
+		}
+	}
+}
+
+func TestLargest(t *testing.T) {
+	var list []digestDelegation
+	for i, amount := range []float64{5, 1, 8, 3, 8} {
+		list = largest(list, digestDelegation{Amount: amount, Height: int64(i)})
+	}
+
+	want := []digestDelegation{{8, 2}, {8, 4}, {5, 0}}
+	if len(list) != len(want) {
+		t.Fatalf("largest = %v, want %v", list, want)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Fatalf("largest = %v, want %v", list, want)
+		}
+	}
+}
+
+func TestChainStatsReset(t *testing.T) {
+	st := newChainStats()
+	st.block(true)
+	st.block(true)
+	st.block(false)
+	st.alert()
+	st.delegation(10, 1)
+	st.undelegation(4, 2)
+	st.setRank(&cosmosblocks.ValidatorRank{Rank: 3})
+
+	got := st.reset()
+	if got.signed != 2 || got.missed != 1 || got.alerts != 1 || got.delegated != 10 || got.undelegated != 4 {
+		t.Fatalf("unexpected stats: %+v", got)
+	}
+
+	next := st.reset()
+	if next.signed != 0 || next.missed != 0 || next.alerts != 0 || len(next.delegations) != 0 {
+		t.Fatalf("stats not reset: %+v", next)
+	}
+	if next.rank == nil || next.rank.Rank != 3 {
+		t.Fatal("rank not kept")
+	}
+}
+
+func TestDigestRender(t *testing.T) {
+	s := &service{cfg: &Config{Digest: &DigestConfig{Schedule: "weekly"}}}
+	chain := Chain{Name: "juno"}
+	chain.Token.Label = "JUNO"
+
+	tests := []struct {
+		name  string
+		st    digestStats
+		lines []string
+	}{
+		{
+			"no block",
+			digestStats{since: time.Now()},
+			[]string{"Blocks: 0 signed, 0 missed, uptime n/a"},
+		},
+		{
+			"uptime",
+			digestStats{since: time.Now(), signed: 999, missed: 1},
+			[]string{"Blocks: 999 signed, 1 missed, uptime 99.90%"},
+		},
+		{
+			"delegations",
+			digestStats{
+				since:         time.Now(),
+				alerts:        2,
+				delegated:     150,
+				undelegated:   200,
+				delegations:   []digestDelegation{{100, 12}, {50, 10}},
+				undelegations: []digestDelegation{{200, 11}},
+			},
+			[]string{
+				"Alerts fired: 2",
+				"Delegations: +150.00 JUNO, undelegations: -200.00 JUNO, net flow: -50.00 JUNO",
+				"Largest delegations: 100.00 JUNO (#12), 50.00 JUNO (#10)",
+				"Largest undelegations: 200.00 JUNO (#11)",
+			},
+		},
+		{
+			"rank",
+			digestStats{since: time.Now(), rank: &cosmosblocks.ValidatorRank{
+				Rank: 12, Validators: 150, VotingPower: 25, TotalVotingPower: 1000,
+			}},
+			[]string{"Rank: #12/150, voting power 25 (2.50%)"},
+		},
+		{
+			"inactive",
+			digestStats{since: time.Now(), rank: &cosmosblocks.ValidatorRank{}},
+			[]string{"Rank: not in the active set"},
+		},
+	}
+	for _, tt := range tests {
+		msg := s.digest(chain, tt.st)
+		if msg.Title != "juno weekly digest" {
+			t.Errorf("%s: title %q", tt.name, msg.Title)
+		}
+		lines := strings.Split(msg.Msg, "\n")
+		for _, want := range tt.lines {
+			found := false
+			for _, line := range lines {
+				found = found || line == want
+			}
+			if !found {
+				t.Errorf("%s: line %q not found in\n%s", tt.name, want, msg.Msg)
+			}
+		}
+	}
+}
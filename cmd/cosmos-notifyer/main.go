@@ -14,6 +14,9 @@ type service struct {
 	cfg *Config
 
 	notify *notifyer.Client
+
+	// stats are the digest statistics by chain name
+	stats map[string]*chainStats
 }
 
 func (s *service) parseConfig(c *cli.Context) error {
@@ -49,6 +52,7 @@ func main() {
 				Flags:  globalFlags,
 				Before: s.parseConfig,
 			},
+			silenceCommand(),
 		},
 	}
 	app.Flags = globalFlags
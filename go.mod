@@ -9,6 +9,7 @@ require (
 	github.com/sirupsen/logrus v1.9.0
 	github.com/tendermint/tendermint v0.34.22
 	github.com/urfave/cli/v2 v2.23.0
+	go.etcd.io/bbolt v1.3.6
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -97,7 +98,6 @@ require (
 	github.com/tendermint/tm-db v0.6.7 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	github.com/zondax/hid v0.9.0 // indirect
-	go.etcd.io/bbolt v1.3.6 // indirect
 	golang.org/x/crypto v0.0.0-20220722155217-630584e8d5aa // indirect
 	golang.org/x/exp v0.0.0-20221028150844-83b7d23a625f // indirect
 	golang.org/x/net v0.0.0-20220812174116-3211cb980234 // indirect
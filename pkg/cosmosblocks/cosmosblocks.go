@@ -2,6 +2,7 @@ package cosmosblocks
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -151,6 +152,7 @@ func (c Client) Start(ctx context.Context) error {
 					continue
 				}
 				b.Logs = append(b.Logs, &logs)
+				b.TxHashes = append(b.TxHashes, fmt.Sprintf("%X", blockTX.Hash()))
 			}
 
 			c.BlockCh <- &b
@@ -1,18 +1,19 @@
 package cosmosblocks
 
 import (
+	"bytes"
 	"context"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
 	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/juju/errors"
-	"github.com/tendermint/tendermint/libs/bytes"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 )
 
 type Validator staking.QueryValidatorResponse
 
-func (v Validator) GetAddress() (bytes.HexBytes, error) {
+func (v Validator) GetAddress() (tmbytes.HexBytes, error) {
 	pk := ed25519.PubKey{}
 	err := pk.Unmarshal(v.Validator.ConsensusPubkey.Value)
 	if err != nil {
@@ -50,3 +51,43 @@ func (c *Client) QueryValidator(valoper string) (*Validator, error) {
 	val := Validator(valResp)
 	return &val, nil
 }
+
+// ValidatorRank is the position of a validator in the active set
+type ValidatorRank struct {
+	// Rank start at 1, 0 when the validator is not in the active set
+	Rank       int
+	Validators int
+
+	VotingPower      int64
+	TotalVotingPower int64
+}
+
+// QueryValidatorRank rank the validator by voting power in the active set
+func (c *Client) QueryValidatorRank(valconsAddr []byte) (*ValidatorRank, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rank := &ValidatorRank{}
+	page, perPage := 1, 100
+	for {
+		resp, err := c.rpcClient.Validators(ctx, nil, &page, &perPage)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		// validators are sorted by voting power
+		for i, val := range resp.Validators {
+			if bytes.Equal(val.Address.Bytes(), valconsAddr) {
+				rank.Rank = (page-1)*perPage + i + 1
+				rank.VotingPower = val.VotingPower
+			}
+			rank.TotalVotingPower += val.VotingPower
+		}
+		rank.Validators = resp.Total
+
+		if page*perPage >= resp.Total {
+			return rank, nil
+		}
+		page++
+	}
+}
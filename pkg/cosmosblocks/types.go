@@ -10,12 +10,13 @@ var (
 )
 
 type MsgDelegate struct {
-	// TODO: In  Events messages, there is a staking
-	// From string
-
 	ValoperAddr string
 	Amount      string
 	Share       string
+
+	// Delegator is the sender of the message
+	Delegator string
+	TxHash    string
 }
 
 func (msg MsgDelegate) GetAmount() float64 {
@@ -27,6 +28,10 @@ func (msg MsgDelegate) GetAmount() float64 {
 type MsgUndelegate struct {
 	ValoperAddr string
 	Amount      string
+
+	// Delegator is the sender of the message
+	Delegator string
+	TxHash    string
 }
 
 func (msg MsgUndelegate) GetAmount() float64 {
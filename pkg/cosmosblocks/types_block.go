@@ -10,6 +10,8 @@ import (
 type Block struct {
 	Event *tmtypes.EventDataNewBlock
 	Logs  []*sdk.ABCIMessageLogs
+	// TxHashes are the hashes of the transactions of Logs, in the same order
+	TxHashes []string
 }
 
 func (b Block) GetHeight() int64 {
@@ -34,10 +36,33 @@ func (b Block) GetValidators() ([]*tmtypes.Address, error) {
 	return addrs, nil
 }
 
+// txHash return the hash of the i-th transaction of Logs
+func (b Block) txHash(i int) string {
+	if i < len(b.TxHashes) {
+		return b.TxHashes[i]
+	}
+	return ""
+}
+
+// messageSender return the sender of a message, i.e. the delegator
+func messageSender(log sdk.ABCIMessageLog) string {
+	for _, e := range log.Events {
+		if e.Type != "message" {
+			continue
+		}
+		for _, attr := range e.GetAttributes() {
+			if attr.Key == "sender" {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
 func (b Block) GetMsgDelegate() []*MsgDelegate {
 	ret := make([]*MsgDelegate, 0)
 
-	for _, log0 := range b.Logs {
+	for txIdx, log0 := range b.Logs {
 		if log0 == nil {
 			continue
 		}
@@ -51,6 +76,8 @@ func (b Block) GetMsgDelegate() []*MsgDelegate {
 							ValoperAddr: attributes[i].Value,
 							Amount:      attributes[i+1].Value,
 							Share:       attributes[i+2].Value,
+							Delegator:   messageSender(log),
+							TxHash:      b.txHash(txIdx),
 						}
 						ret = append(ret, msg)
 
@@ -69,7 +96,7 @@ func (b Block) GetMsgDelegate() []*MsgDelegate {
 func (b Block) GetMsgUndelegate() []*MsgUndelegate {
 	ret := make([]*MsgUndelegate, 0, 10)
 
-	for _, log0 := range b.Logs {
+	for txIdx, log0 := range b.Logs {
 		if log0 == nil {
 			continue
 		}
@@ -83,6 +110,8 @@ func (b Block) GetMsgUndelegate() []*MsgUndelegate {
 						msg := MsgUndelegate{
 							ValoperAddr: attributes[i].Value,
 							Amount:      attributes[i+2].Value,
+							Delegator:   messageSender(log),
+							TxHash:      b.txHash(txIdx),
 						}
 						ret = append(ret, &msg)
 
@@ -98,6 +127,8 @@ func (b Block) GetMsgUndelegate() []*MsgUndelegate {
 						msg := &MsgUndelegate{
 							ValoperAddr: attributes[i].Value,
 							Amount:      attributes[i+1].Value,
+							Delegator:   messageSender(log),
+							TxHash:      b.txHash(txIdx),
 						}
 						ret = append(ret, msg)
 
@@ -0,0 +1,132 @@
+package notifyer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const alertmanagerDefaultAlertTimeout = 24 * time.Hour
+
+// AlertmanagerClient is complient with the Service interface
+//
+// Alerts are pushed to the Alertmanager v2 API with the chain, validator,
+// condition and severity labels, recoveries push the same alert with endsAt.
+// Open alerts are pushed again every AlertTimeout/2 so that Alertmanager
+// does not resolve them, incidents open before a restart are only pushed
+// again when the database is enabled. Delegations and infos are not sent.
+type AlertmanagerClient struct {
+	// URL of Alertmanager, e.g. http://localhost:9093
+	URL string `yaml:"url"`
+	// Labels are added to every alert, e.g. team: ops
+	Labels map[string]string `yaml:"labels"`
+	// AlertTimeout resolve an alert not pushed again, e.g. when
+	// cosmos-notifyer is stopped (default 24h)
+	AlertTimeout time.Duration `yaml:"alert_timeout"`
+}
+
+func (c AlertmanagerClient) validate() error {
+	if c.URL == "" {
+		return errors.New("alertmanager: url is required")
+	}
+	return nil
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// labels return the labels identifying the condition of e
+func (c AlertmanagerClient) labels(e Event) map[string]string {
+	labels := map[string]string{}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = "CosmosNotifyer"
+	labels["chain"] = e.Chain
+	labels["validator"] = e.Validator
+	labels["condition"] = string(e.Kind)
+	labels["severity"] = string(e.Severity)
+	if e.Kind == KindFlapping {
+		labels["flapping"] = e.Fields["condition"]
+	}
+	if e.ID != "" {
+		labels["id"] = e.ID
+	}
+	return labels
+}
+
+func (c AlertmanagerClient) annotations(msg string, e Event) map[string]string {
+	annotations := map[string]string{
+		"summary": msg,
+	}
+	if e.Moniker != "" {
+		annotations["moniker"] = e.Moniker
+	}
+	if e.Height != 0 {
+		annotations["height"] = strconv.FormatInt(e.Height, 10)
+	}
+	for k, v := range e.Fields {
+		if v != "" {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
+
+func (c AlertmanagerClient) push(alert alertmanagerAlert) error {
+	url := strings.TrimSuffix(c.URL, "/") + "/api/v2/alerts"
+	if err := postJSON(url, []alertmanagerAlert{alert}, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c AlertmanagerClient) alertTimeout() time.Duration {
+	if c.AlertTimeout == 0 {
+		return alertmanagerDefaultAlertTimeout
+	}
+	return c.AlertTimeout
+}
+
+// refreshInterval push the open alerts again before they time out
+func (c AlertmanagerClient) refreshInterval() time.Duration {
+	return c.alertTimeout() / 2
+}
+
+func (c AlertmanagerClient) Alert(msg AlertMsg) error {
+	return c.push(alertmanagerAlert{
+		Labels:       c.labels(msg.Event),
+		Annotations:  c.annotations(msg.Msg, msg.Event),
+		StartsAt:     msg.Time.Format(time.RFC3339),
+		EndsAt:       time.Now().Add(c.alertTimeout()).Format(time.RFC3339),
+		GeneratorURL: msg.ExplorerURL,
+	})
+}
+
+func (c AlertmanagerClient) Recover(msg RecoverMsg) error {
+	return c.push(alertmanagerAlert{
+		Labels:       c.labels(msg.Event),
+		Annotations:  c.annotations(msg.Msg, msg.Event),
+		EndsAt:       msg.Time.Format(time.RFC3339),
+		GeneratorURL: msg.ExplorerURL,
+	})
+}
+
+func (c AlertmanagerClient) Delegation(msg DelegationMsg) error {
+	return nil
+}
+
+func (c AlertmanagerClient) UnDelegation(msg UnDelegationMsg) error {
+	return nil
+}
+
+func (c AlertmanagerClient) Info(msg InfoMsg) error {
+	return nil
+}
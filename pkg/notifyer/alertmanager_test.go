@@ -0,0 +1,108 @@
+package notifyer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// alertmanagerServer record the alerts pushed to /api/v2/alerts
+type alertmanagerServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	alerts []alertmanagerAlert
+}
+
+func newAlertmanagerServer(t *testing.T) *alertmanagerServer {
+	s := &alertmanagerServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alerts := []alertmanagerAlert{}
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Error(err)
+		}
+		s.mu.Lock()
+		s.alerts = append(s.alerts, alerts...)
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *alertmanagerServer) list() []alertmanagerAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]alertmanagerAlert(nil), s.alerts...)
+}
+
+func TestAlertmanagerLabels(t *testing.T) {
+	c := AlertmanagerClient{Labels: map[string]string{"team": "ops"}}
+
+	labels := c.labels(Event{Chain: "juno", Kind: KindExternal, Severity: SeverityCritical, ID: "3f2a"})
+	if labels["team"] != "ops" || labels["chain"] != "juno" || labels["condition"] != "external" ||
+		labels["severity"] != "critical" || labels["id"] != "3f2a" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if _, ok := c.labels(Event{Chain: "juno", Kind: KindJailed})["id"]; ok {
+		t.Fatal("id label without ID")
+	}
+}
+
+func TestAlertmanagerRefresh(t *testing.T) {
+	srv := newAlertmanagerServer(t)
+	am := &AlertmanagerClient{URL: srv.URL, AlertTimeout: time.Hour}
+	rec := &recorder{}
+	c, err := NewClient(Config{
+		Destinations: []DestinationConfig{
+			{Name: "alertmanager", Backend: am},
+			{Name: "rec", Backend: rec},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.incidents.refreshInterval != 30*time.Minute {
+		t.Fatalf("refresh interval %s", c.incidents.refreshInterval)
+	}
+
+	e := Event{Chain: "juno", Kind: KindJailed}
+	c.Alert(AlertMsg{Event: e})
+
+	// not due yet
+	c.incidents.check()
+	if n := len(srv.list()); n != 1 {
+		t.Fatalf("%d alerts pushed, want 1", n)
+	}
+
+	c.incidents.mu.Lock()
+	c.incidents.open[e.Key()].SentAt = time.Now().Add(-time.Hour)
+	c.incidents.mu.Unlock()
+	c.incidents.check()
+
+	c.Recover(RecoverMsg{Event: e})
+
+	alerts := srv.list()
+	if len(alerts) != 3 {
+		t.Fatalf("%d alerts pushed, want 3", len(alerts))
+	}
+	endsAt, err := time.Parse(time.RFC3339, alerts[1].EndsAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(endsAt) < 50*time.Minute {
+		t.Fatalf("refreshed alert ends at %s", endsAt)
+	}
+	if alerts[2].StartsAt != "" {
+		t.Fatal("recovery pushed as an alert")
+	}
+
+	// only the backends whose alerts expire are refreshed
+	if got, want := rec.list(), []string{"alert:jailed", "recover:jailed"}; !equal(got, want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+}
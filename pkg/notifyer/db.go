@@ -0,0 +1,30 @@
+package notifyer
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openDB open the bolt database holding the notifyer state and create its buckets
+func openDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Annotatef(err, "database %s", path)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{outboxBucket, silencesBucket, incidentsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Trace(err)
+	}
+	return db, nil
+}
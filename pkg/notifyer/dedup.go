@@ -0,0 +1,297 @@
+package notifyer
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const dedupDefaultFlapWindow = 10 * time.Minute
+
+// DedupConfig configure the alert deduplication and flap suppression,
+// conditions are identified by Event.Key (chain/kind).
+type DedupConfig struct {
+	// AlertAfter is how long a condition must hold before it is alerted
+	AlertAfter time.Duration `yaml:"alert_after"`
+	// RecoverAfter is how long a condition must be healthy before it is recovered
+	RecoverAfter time.Duration `yaml:"recover_after"`
+
+	// FlapThreshold is the number of state changes within FlapWindow
+	// (default 10m) after which a condition is flapping, 0 disable it.
+	// A flapping condition send a single KindFlapping alert and is muted
+	// until it has been stable for FlapWindow.
+	FlapThreshold int           `yaml:"flap_threshold"`
+	FlapWindow    time.Duration `yaml:"flap_window"`
+}
+
+func (cfg DedupConfig) enabled() bool {
+	return cfg.AlertAfter > 0 || cfg.RecoverAfter > 0 || cfg.FlapThreshold > 0
+}
+
+// condition is the dedup state of an Event.Key
+type condition struct {
+	// firing is the latest state reported by the caller
+	firing bool
+	// notified is true when the alert has been sent and not recovered yet
+	notified bool
+
+	lastAlert   AlertMsg
+	lastRecover RecoverMsg
+
+	pendingAlert   *time.Timer
+	pendingRecover *time.Timer
+
+	transitions []time.Time
+	flapping    bool
+	flapTimer   *time.Timer
+}
+
+// dedup hold down alerts and recoveries and suppress flapping conditions,
+// messages to send are passed to emit.
+type dedup struct {
+	cfg  DedupConfig
+	emit func(typ msgType, msg interface{})
+
+	mu         sync.Mutex
+	conditions map[string]*condition
+}
+
+func newDedup(cfg DedupConfig, emit func(typ msgType, msg interface{})) *dedup {
+	if cfg.FlapWindow == 0 {
+		cfg.FlapWindow = dedupDefaultFlapWindow
+	}
+	return &dedup{
+		cfg:        cfg,
+		emit:       emit,
+		conditions: map[string]*condition{},
+	}
+}
+
+// alert return true when msg must be sent right away
+func (d *dedup) alert(msg AlertMsg) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := msg.Key()
+	c, ok := d.conditions[key]
+	if !ok {
+		c = &condition{}
+		d.conditions[key] = c
+	}
+	c.lastAlert = msg
+
+	if !c.firing {
+		c.firing = true
+		d.transition(key, c)
+	}
+
+	if c.flapping {
+		return false
+	}
+
+	if c.pendingRecover != nil {
+		// came back before the recovery was confirmed, the alert is still open
+		c.pendingRecover.Stop()
+		c.pendingRecover = nil
+		return false
+	}
+
+	if c.notified {
+		// follow up of an open alert
+		return true
+	}
+
+	if d.cfg.AlertAfter == 0 {
+		c.notified = true
+		return true
+	}
+
+	if c.pendingAlert == nil {
+		c.pendingAlert = time.AfterFunc(d.cfg.AlertAfter, func() {
+			d.confirmAlert(key)
+		})
+	}
+	return false
+}
+
+// recover return true when msg must be sent right away
+func (d *dedup) recover(msg RecoverMsg) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := msg.Key()
+	c, ok := d.conditions[key]
+	if !ok {
+		// never seen firing (e.g. after a restart), let it through
+		return true
+	}
+	c.lastRecover = msg
+
+	if c.firing {
+		c.firing = false
+		d.transition(key, c)
+	}
+
+	if c.flapping {
+		return false
+	}
+
+	if c.pendingAlert != nil {
+		// recovered before the alert was confirmed, nothing was sent
+		c.pendingAlert.Stop()
+		c.pendingAlert = nil
+		d.forget(key, c)
+		return false
+	}
+
+	if !c.notified {
+		return false
+	}
+
+	if d.cfg.RecoverAfter == 0 {
+		c.notified = false
+		d.forget(key, c)
+		return true
+	}
+
+	if c.pendingRecover == nil {
+		c.pendingRecover = time.AfterFunc(d.cfg.RecoverAfter, func() {
+			d.confirmRecover(key)
+		})
+	}
+	return false
+}
+
+func (d *dedup) confirmAlert(key string) {
+	d.mu.Lock()
+	c, ok := d.conditions[key]
+	if !ok || c.pendingAlert == nil || !c.firing || c.flapping {
+		d.mu.Unlock()
+		return
+	}
+	c.pendingAlert = nil
+	c.notified = true
+	msg := c.lastAlert
+	d.mu.Unlock()
+
+	d.emit(msgAlert, msg)
+}
+
+func (d *dedup) confirmRecover(key string) {
+	d.mu.Lock()
+	c, ok := d.conditions[key]
+	if !ok || c.pendingRecover == nil || c.firing || c.flapping {
+		d.mu.Unlock()
+		return
+	}
+	c.pendingRecover = nil
+	c.notified = false
+	msg := c.lastRecover
+	d.forget(key, c)
+	d.mu.Unlock()
+
+	d.emit(msgRecover, msg)
+}
+
+// transition record a state change and start flapping when the threshold is
+// reached, must be called with the lock held
+func (d *dedup) transition(key string, c *condition) {
+	if d.cfg.FlapThreshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+	transitions := c.transitions[:0]
+	for _, t := range c.transitions {
+		if now.Sub(t) < d.cfg.FlapWindow {
+			transitions = append(transitions, t)
+		}
+	}
+	c.transitions = append(transitions, now)
+
+	if c.flapping {
+		// wait for the condition to be stable for a whole window
+		c.flapTimer.Reset(d.cfg.FlapWindow)
+		return
+	}
+
+	if len(c.transitions) < d.cfg.FlapThreshold {
+		return
+	}
+
+	c.flapping = true
+	if c.pendingAlert != nil {
+		c.pendingAlert.Stop()
+		c.pendingAlert = nil
+	}
+	if c.pendingRecover != nil {
+		c.pendingRecover.Stop()
+		c.pendingRecover = nil
+	}
+	c.flapTimer = time.AfterFunc(d.cfg.FlapWindow, func() {
+		d.stopFlapping(key)
+	})
+
+	logrus.WithFields(logrus.Fields{
+		"condition": key,
+		"changes":   len(c.transitions),
+	}).Warn("condition is flapping")
+
+	msg := AlertMsg{Event: d.flappingEvent(c)}
+	go d.emit(msgAlert, msg)
+}
+
+// stopFlapping send the end of the flapping and the current state of the condition
+func (d *dedup) stopFlapping(key string) {
+	d.mu.Lock()
+	c, ok := d.conditions[key]
+	if !ok || !c.flapping {
+		d.mu.Unlock()
+		return
+	}
+	c.flapping = false
+	c.transitions = nil
+	flapping := d.flappingEvent(c)
+
+	var typ msgType
+	var msg interface{}
+	if c.firing && !c.notified {
+		c.notified = true
+		typ, msg = msgAlert, c.lastAlert
+	} else if !c.firing && c.notified {
+		c.notified = false
+		typ, msg = msgRecover, c.lastRecover
+		d.forget(key, c)
+	}
+	d.mu.Unlock()
+
+	d.emit(msgRecover, RecoverMsg{Event: flapping})
+	if msg != nil {
+		d.emit(typ, msg)
+	}
+}
+
+// flappingEvent return the KindFlapping event of the condition
+func (d *dedup) flappingEvent(c *condition) Event {
+	e := c.lastAlert.Event
+	e.Kind = KindFlapping
+	e.Severity = DefaultSeverity(KindFlapping)
+	e.Time = time.Now().UTC()
+	e.Fields = map[string]string{
+		"condition":     string(c.lastAlert.Kind),
+		"state_changes": strconv.Itoa(len(c.transitions)),
+		"window":        humanizeDuration(d.cfg.FlapWindow),
+	}
+	return e
+}
+
+// forget drop the state of a healthy condition, must be called with the lock held
+func (d *dedup) forget(key string, c *condition) {
+	if d.cfg.FlapThreshold > 0 && len(c.transitions) > 0 {
+		// keep the transitions to detect flapping
+		return
+	}
+	delete(d.conditions, key)
+}
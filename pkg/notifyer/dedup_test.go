@@ -0,0 +1,197 @@
+package notifyer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// emitted record the messages emitted by a dedup
+type emitted struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (e *emitted) emit(typ msgType, msg interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.msgs = append(e.msgs, string(typ)+":"+string(msgEvent(msg).Kind))
+}
+
+func (e *emitted) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.msgs...)
+}
+
+func equal(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func jailed() Event {
+	return Event{Chain: "juno", Kind: KindJailed}
+}
+
+func TestDedupPassThrough(t *testing.T) {
+	out := &emitted{}
+	d := newDedup(DedupConfig{}, out.emit)
+
+	steps := []struct {
+		name string
+		send func() bool
+		want bool
+	}{
+		{"recover never seen", func() bool { return d.recover(RecoverMsg{Event: jailed()}) }, true},
+		{"alert", func() bool { return d.alert(AlertMsg{Event: jailed()}) }, true},
+		{"follow-up", func() bool { return d.alert(AlertMsg{Event: jailed()}) }, true},
+		{"recover", func() bool { return d.recover(RecoverMsg{Event: jailed()}) }, true},
+		{"recover again", func() bool { return d.recover(RecoverMsg{Event: jailed()}) }, true},
+	}
+	for _, s := range steps {
+		if got := s.send(); got != s.want {
+			t.Fatalf("%s: got %v, want %v", s.name, got, s.want)
+		}
+	}
+	if msgs := out.list(); len(msgs) != 0 {
+		t.Fatalf("unexpected emitted messages: %v", msgs)
+	}
+}
+
+func TestDedupAlertAfter(t *testing.T) {
+	out := &emitted{}
+	d := newDedup(DedupConfig{AlertAfter: 50 * time.Millisecond}, out.emit)
+
+	// recovered before alert_after, nothing is sent
+	if d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("alert sent before alert_after")
+	}
+	if d.recover(RecoverMsg{Event: jailed()}) {
+		t.Fatal("recovery of an unsent alert")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if msgs := out.list(); len(msgs) != 0 {
+		t.Fatalf("unexpected emitted messages: %v", msgs)
+	}
+
+	// held for alert_after, the alert is sent then its follow-ups
+	if d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("alert sent before alert_after")
+	}
+	eventually(t, "the held alert", func() bool {
+		return equal(out.list(), []string{"alert:jailed"})
+	})
+	if !d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("follow-up of an open alert not sent")
+	}
+	if !d.recover(RecoverMsg{Event: jailed()}) {
+		t.Fatal("recovery of an open alert not sent")
+	}
+}
+
+func TestDedupRecoverAfter(t *testing.T) {
+	out := &emitted{}
+	d := newDedup(DedupConfig{RecoverAfter: 50 * time.Millisecond}, out.emit)
+
+	if !d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("alert not sent")
+	}
+
+	// firing again before recover_after, the alert is still open
+	if d.recover(RecoverMsg{Event: jailed()}) {
+		t.Fatal("recovery sent before recover_after")
+	}
+	if d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("alert sent again while open")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if msgs := out.list(); len(msgs) != 0 {
+		t.Fatalf("unexpected emitted messages: %v", msgs)
+	}
+
+	if d.recover(RecoverMsg{Event: jailed()}) {
+		t.Fatal("recovery sent before recover_after")
+	}
+	eventually(t, "the held recovery", func() bool {
+		return equal(out.list(), []string{"recover:jailed"})
+	})
+}
+
+func TestDedupFlapping(t *testing.T) {
+	out := &emitted{}
+	d := newDedup(DedupConfig{
+		FlapThreshold: 4,
+		FlapWindow:    100 * time.Millisecond,
+	}, out.emit)
+
+	sent := []bool{
+		d.alert(AlertMsg{Event: jailed()}),
+		d.recover(RecoverMsg{Event: jailed()}),
+		d.alert(AlertMsg{Event: jailed()}),
+		d.recover(RecoverMsg{Event: jailed()}),
+		d.alert(AlertMsg{Event: jailed()}),
+		d.recover(RecoverMsg{Event: jailed()}),
+		d.alert(AlertMsg{Event: jailed()}),
+	}
+	want := []bool{true, true, true, false, false, false, false}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Fatalf("message #%d: sent %v, want %v", i, sent[i], want[i])
+		}
+	}
+	eventually(t, "the flapping alert", func() bool {
+		return equal(out.list(), []string{"alert:flapping"})
+	})
+
+	// stable for a whole window, the flapping is recovered and the alert,
+	// already open when the flapping started, is not sent again
+	eventually(t, "the end of the flapping", func() bool {
+		return equal(out.list(), []string{"alert:flapping", "recover:flapping"})
+	})
+	if !d.alert(AlertMsg{Event: jailed()}) {
+		t.Fatal("follow-up not sent after flapping")
+	}
+}
+
+func TestDedupFlappingEndsFiring(t *testing.T) {
+	out := &emitted{}
+	d := newDedup(DedupConfig{
+		FlapThreshold: 3,
+		FlapWindow:    100 * time.Millisecond,
+	}, out.emit)
+
+	d.alert(AlertMsg{Event: jailed()})
+	d.recover(RecoverMsg{Event: jailed()})
+	d.alert(AlertMsg{Event: jailed()})
+
+	// the condition stopped flapping while firing, its alert is sent
+	eventually(t, "the end of the flapping", func() bool {
+		return equal(out.list(), []string{"alert:flapping", "recover:flapping", "alert:jailed"})
+	})
+}
+
+func TestFlappingEvent(t *testing.T) {
+	d := newDedup(DedupConfig{FlapThreshold: 2}, func(msgType, interface{}) {})
+	c := &condition{
+		lastAlert:   AlertMsg{Event: Event{Chain: "juno", Kind: KindMissedBlocks, Moniker: "nysa"}},
+		transitions: make([]time.Time, 5),
+	}
+
+	e := d.flappingEvent(c)
+	if e.Kind != KindFlapping || e.Severity != DefaultSeverity(KindFlapping) {
+		t.Fatalf("kind %s severity %s", e.Kind, e.Severity)
+	}
+	if e.Fields["condition"] != "missed-blocks" || e.Fields["state_changes"] != "5" || e.Fields["window"] != "10m" {
+		t.Fatalf("unexpected fields: %v", e.Fields)
+	}
+	if e.Key() != "juno/flapping/missed-blocks" {
+		t.Fatalf("key %s", e.Key())
+	}
+}
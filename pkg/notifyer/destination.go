@@ -0,0 +1,85 @@
+package notifyer
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DestinationConfig configure a named destination. Type select the backend
+// and the other keys are its settings, e.g.
+//
+//	name: community
+//	type: discord
+//	chains: [juno]
+//	kinds: [delegation, undelegation]
+//	webhook: https://discord.com/api/webhooks/xxx
+//
+// A destination only receive the events matching its filters (Chains, Kinds
+// and Severities), an empty filter match every event. Types are registered
+// with Register, Backend can be set instead of Type when building the config
+// in Go.
+type DestinationConfig struct {
+	Name    string  `yaml:"name"`
+	Type    string  `yaml:"type"`
+	Backend Backend `yaml:"-"`
+
+	Chains     []string    `yaml:"chains"`
+	Kinds      []EventKind `yaml:"kinds"`
+	Severities []Severity  `yaml:"severities"`
+
+	// settings is the whole YAML node, decoded by the backend
+	settings yaml.Node
+}
+
+func (d *DestinationConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain DestinationConfig
+	if err := node.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	d.settings = *node
+	return nil
+}
+
+// destinationKeys are the keys of every destination, not backend settings
+var destinationKeys = []string{"name", "type", "chains", "kinds", "severities"}
+
+// Decode the backend settings into v, whose fields have yaml tags. Unknown
+// settings are rejected, e.g. a misspelled key.
+func (d DestinationConfig) Decode(v interface{}) error {
+	if d.settings.Kind == 0 {
+		return nil
+	}
+
+	settings := d.settings
+	if settings.Kind == yaml.MappingNode {
+		settings.Content = nil
+		for i := 0; i+1 < len(d.settings.Content); i += 2 {
+			if !contains(destinationKeys, d.settings.Content[i].Value) {
+				settings.Content = append(settings.Content, d.settings.Content[i], d.settings.Content[i+1])
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(&settings)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// filter return the route matching the events accepted by the destination
+func (d DestinationConfig) filter() Route {
+	return Route{
+		Chains:     d.Chains,
+		Kinds:      d.Kinds,
+		Severities: d.Severities,
+	}
+}
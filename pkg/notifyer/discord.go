@@ -2,78 +2,318 @@ package notifyer
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gtuk/discordwebhook"
 	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
 )
 
-// DiscordClient is complient with the Service interface
+const (
+	discordUsername = "cosmos-notifyer"
+	// discordMaxFields is the maximum number of fields of an embed
+	discordMaxFields = 25
+	// discordMaxThreadName is the maximum length of a thread name
+	discordMaxThreadName = 100
+
+	discordColorAlert        = 0xED4245
+	discordColorRecover      = 0x57F287
+	discordColorDelegation   = 0x3498DB
+	discordColorUnDelegation = 0xE67E22
+	discordColorInfo         = 0x95A5A6
+)
+
+// DiscordClient is complient with the Service interface, messages are sent
+// as embeds through a rate limited queue per webhook. The delegations,
+// undelegations and infos waiting in the outbox are coalesced into a single
+// message, e.g. the delegations of a block.
+//
+// When ThreadsWebhook (a forum channel webhook) is set, every incident get
+// its own thread, e.g. "juno: missed blocks", holding the alert, its
+// follow-ups and the recovery, Webhook then only get a one-line pointer to
+// the thread. Open threads are kept in memory, an incident open before a
+// restart continue in a new thread.
 type DiscordClient struct {
-	Webhook string
+	Webhook        string `yaml:"webhook"`
+	ThreadsWebhook string `yaml:"threads_webhook"`
 }
 
-func (c DiscordClient) Alert(msg AlertMsg) error {
-	username := "cosmos-notifyer"
-	content := ":rotating_light: " + msg.Msg
+func (c DiscordClient) validate() error {
+	if c.Webhook == "" && c.ThreadsWebhook == "" {
+		return errors.New("discord: webhook or threads_webhook is required")
+	}
+	return nil
+}
+
+// discordThreads map the open incidents to their thread, keyed by threads
+// webhook then by Event.Key
+var discordThreads = struct {
+	sync.Mutex
+	threads map[string]map[string]string
+}{threads: map[string]map[string]string{}}
+
+// thread return the thread of the incident key, if any
+func (c DiscordClient) thread(key string) string {
+	discordThreads.Lock()
+	defer discordThreads.Unlock()
+	return discordThreads.threads[c.ThreadsWebhook][key]
+}
+
+// setThread record the thread of the incident key, an empty thread close it
+func (c DiscordClient) setThread(key string, thread string) {
+	discordThreads.Lock()
+	defer discordThreads.Unlock()
 
-	message := discordwebhook.Message{
-		Username: &username,
-		Content:  &content,
+	if thread == "" {
+		delete(discordThreads.threads[c.ThreadsWebhook], key)
+		return
+	}
+	if discordThreads.threads[c.ThreadsWebhook] == nil {
+		discordThreads.threads[c.ThreadsWebhook] = map[string]string{}
 	}
+	discordThreads.threads[c.ThreadsWebhook][key] = thread
+}
 
-	err := discordwebhook.SendMessage(c.Webhook, message)
-	if err != nil {
+// discordThreadName return the thread name of an incident, e.g.
+// "juno: missed blocks"
+func discordThreadName(e Event) string {
+	name := strings.ReplaceAll(string(e.Kind), "-", " ")
+	if e.Kind == KindFlapping {
+		name = strings.ReplaceAll(e.Fields["condition"], "-", " ") + " flapping"
+	}
+	if e.Chain != "" {
+		name = e.Chain + ": " + name
+	}
+	if r := []rune(name); len(r) > discordMaxThreadName {
+		name = string(r[:discordMaxThreadName])
+	}
+	return name
+}
+
+// discordField return an embed field
+func discordField(name string, value string, inline bool) discordwebhook.Field {
+	return discordwebhook.Field{
+		Name:   &name,
+		Value:  &value,
+		Inline: &inline,
+	}
+}
+
+// discordFields return the embed fields of e: chain, validator, height,
+// amount, delegator and tx hash first then the other fields by key
+func discordFields(e Event, amount string) []discordwebhook.Field {
+	fields := make([]discordwebhook.Field, 0, 6+len(e.Fields))
+
+	add := func(name string, value string, inline bool) {
+		if value != "" && len(fields) < discordMaxFields {
+			fields = append(fields, discordField(name, value, inline))
+		}
+	}
+
+	add("Chain", e.Chain, true)
+	validator := e.Moniker
+	if validator == "" {
+		validator = e.Validator
+	}
+	add("Validator", validator, true)
+	if e.Height != 0 {
+		add("Height", strconv.FormatInt(e.Height, 10), true)
+	}
+	add("Amount", amount, true)
+	add("Delegator", e.Fields["delegator"], false)
+	add("Tx hash", e.Fields["tx_hash"], false)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		if k != "delegator" && k != "tx_hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, e.Fields[k], true)
+	}
+	return fields
+}
+
+// embed return the embed of a message, linked to the explorer URL of e
+func (c DiscordClient) embed(title string, description string, color int, e Event, amount string) discordwebhook.Embed {
+	colorStr := strconv.Itoa(color)
+	footer := string(e.Kind) + " · " + string(e.Severity)
+	fields := discordFields(e, amount)
+
+	embed := discordwebhook.Embed{
+		Title:  &title,
+		Color:  &colorStr,
+		Fields: &fields,
+		Footer: &discordwebhook.Footer{Text: &footer},
+	}
+	if description != "" {
+		embed.Description = &description
+	}
+	if e.ExplorerURL != "" {
+		url := e.ExplorerURL
+		embed.Url = &url
+	}
+	return embed
+}
+
+// send post the embeds on Webhook and wait until they are delivered, they
+// start a thread when Webhook is not set
+func (c DiscordClient) send(e Event, embeds ...discordwebhook.Embed) error {
+	if c.Webhook == "" && c.ThreadsWebhook != "" {
+		_, err := c.openThread(discordThreadName(e), embeds)
 		return errors.Trace(err)
 	}
-	return nil
+	_, err := discordQueueFor(c.Webhook).send(discordMessage{Embeds: embeds}, "")
+	return errors.Trace(err)
 }
 
-func (c DiscordClient) Recover(msg RecoverMsg) error {
-	username := "cosmos-notifyer"
-	content := ":ok_hand: " + msg.Msg
+// openThread start a thread on ThreadsWebhook with the embeds and return it,
+// it wait for the delivery to get the thread
+func (c DiscordClient) openThread(name string, embeds []discordwebhook.Embed) (string, error) {
+	thread, err := discordQueueFor(c.ThreadsWebhook).send(discordMessage{
+		Embeds:     embeds,
+		ThreadName: name,
+	}, "")
+	if err != nil {
+		return "", errors.Annotatef(err, "discord: open thread %q", name)
+	}
+	if thread == "" {
+		return "", errors.Errorf("discord: no thread created for %q, is the threads webhook in a forum channel ?", name)
+	}
+	return thread, nil
+}
 
-	message := discordwebhook.Message{
-		Username: &username,
-		Content:  &content,
+// sendIncident post the embed into the thread of the incident, the first
+// alert of an incident open the thread and post a pointer on Webhook
+func (c DiscordClient) sendIncident(icon string, e Event, embed discordwebhook.Embed) error {
+	key := e.Key()
+	if thread := c.thread(key); thread != "" {
+		_, err := discordQueueFor(c.ThreadsWebhook).send(discordMessage{
+			Embeds: []discordwebhook.Embed{embed},
+		}, thread)
+		return errors.Annotatef(err, "discord: thread %s", thread)
 	}
 
-	err := discordwebhook.SendMessage(c.Webhook, message)
+	name := discordThreadName(e)
+	thread, err := c.openThread(name, []discordwebhook.Embed{embed})
 	if err != nil {
 		return errors.Trace(err)
 	}
+	c.setThread(key, thread)
+
+	if c.Webhook == "" {
+		return nil
+	}
+	// the alert is posted in the thread, a failed pointer is only logged
+	// since the outbox retry would post the alert again
+	_, err = discordQueueFor(c.Webhook).send(discordMessage{
+		Content: fmt.Sprintf("%s **%s** → <#%s>", icon, name, thread),
+	}, "")
+	if err != nil {
+		logrus.WithError(err).WithField("thread", thread).Warn("discord: thread pointer not posted")
+	}
 	return nil
 }
 
-func (c DiscordClient) Delegation(msg DelegationMsg) error {
-	username := "cosmos-notifyer"
-	content := fmt.Sprintf(":money_mouth: new delegation of %v %s", msg.Amount, msg.Token)
+func (c DiscordClient) Alert(msg AlertMsg) error {
+	embed := c.embed("🚨 Alert: "+string(msg.Kind), msg.Msg, discordColorAlert, msg.Event, "")
+	if c.ThreadsWebhook != "" {
+		return c.sendIncident("🚨", msg.Event, embed)
+	}
+	return c.send(msg.Event, embed)
+}
 
-	message := discordwebhook.Message{
-		Username: &username,
-		Content:  &content,
+func (c DiscordClient) Recover(msg RecoverMsg) error {
+	embed := c.embed("👌 Recovered: "+string(msg.Kind), msg.Msg, discordColorRecover, msg.Event, "")
+	if c.ThreadsWebhook == "" {
+		return c.send(msg.Event, embed)
 	}
 
-	err := discordwebhook.SendMessage(c.Webhook, message)
+	key := msg.Key()
+	thread := c.thread(key)
+	if thread == "" {
+		// no open thread, e.g. the alert was sent before a restart
+		return c.send(msg.Event, embed)
+	}
+	_, err := discordQueueFor(c.ThreadsWebhook).send(discordMessage{
+		Embeds: []discordwebhook.Embed{embed},
+	}, thread)
 	if err != nil {
-		return errors.Trace(err)
+		// the thread is kept so the retry of the recovery is posted in it
+		return errors.Annotatef(err, "discord: thread %s", thread)
 	}
+	c.setThread(key, "")
 	return nil
+}
 
+// msgEmbed return the embed of a delegation, undelegation or info
+func (c DiscordClient) msgEmbed(msg interface{}) (discordwebhook.Embed, Event) {
+	switch m := msg.(type) {
+	case DelegationMsg:
+		amount := fmt.Sprintf("%s %s", humanize(m.Amount), m.Token)
+		return c.embed("🤑 New delegation", m.Msg, discordColorDelegation, m.Event, amount), m.Event
+	case UnDelegationMsg:
+		amount := fmt.Sprintf("%s %s", humanize(m.Amount), m.Token)
+		return c.embed("💸 Lost delegation", m.Msg, discordColorUnDelegation, m.Event, amount), m.Event
+	case InfoMsg:
+		return c.embed("ℹ️ "+m.Title, m.Msg, discordColorInfo, m.Event, ""), m.Event
+	}
+	return discordwebhook.Embed{}, msgEvent(msg)
+}
+
+func (c DiscordClient) Delegation(msg DelegationMsg) error {
+	embed, e := c.msgEmbed(msg)
+	return c.send(e, embed)
 }
 
 func (c DiscordClient) UnDelegation(msg UnDelegationMsg) error {
-	username := "cosmos-notifyer"
-	content := fmt.Sprintf(":money_with_wings: lost delegation of %v %s", msg.Amount, msg.Token)
+	embed, e := c.msgEmbed(msg)
+	return c.send(e, embed)
+}
 
-	message := discordwebhook.Message{
-		Username: &username,
-		Content:  &content,
-	}
+func (c DiscordClient) Info(msg InfoMsg) error {
+	embed, e := c.msgEmbed(msg)
+	return c.send(e, embed)
+}
 
-	err := discordwebhook.SendMessage(c.Webhook, message)
-	if err != nil {
-		return errors.Trace(err)
+// batchable return true for the delegations, undelegations and infos posted
+// on Webhook, the ones opening threads are not coalesced
+func (c DiscordClient) batchable(typ msgType) bool {
+	if c.Webhook == "" {
+		return false
 	}
-	return nil
+	return typ == msgDelegation || typ == msgUnDelegation || typ == msgInfo
+}
+
+// sendBatch post the embeds of msgs in as few messages as possible, it
+// return the number of messages delivered
+func (c DiscordClient) sendBatch(msgs []interface{}) (int, error) {
+	q := discordQueueFor(c.Webhook)
 
+	delivered, n := 0, 0
+	batch := discordRequest{}
+	for _, msg := range msgs {
+		embed, _ := c.msgEmbed(msg)
+		req := discordRequest{message: discordMessage{Embeds: []discordwebhook.Embed{embed}}}
+		if n > 0 && batch.merge(req) {
+			n++
+			continue
+		}
+		if n > 0 {
+			if _, err := q.send(batch.message, ""); err != nil {
+				return delivered, errors.Trace(err)
+			}
+			delivered += n
+		}
+		batch, n = req, 1
+	}
+	if _, err := q.send(batch.message, ""); err != nil {
+		return delivered, errors.Trace(err)
+	}
+	return delivered + n, nil
 }
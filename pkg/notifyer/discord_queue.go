@@ -0,0 +1,312 @@
+package notifyer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gtuk/discordwebhook"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// discordMaxEmbeds, discordMaxEmbedsLength and discordMaxContent are the
+	// limits of a message
+	discordMaxEmbeds       = 10
+	discordMaxEmbedsLength = 6000
+	discordMaxContent      = 2000
+	// discordMaxRetries is the number of retries of a rate limited message
+	discordMaxRetries = 5
+	// discordQueueSize is the number of messages waiting for a webhook
+	discordQueueSize = 1000
+)
+
+var (
+	discordQueuesMu sync.Mutex
+	discordQueues   = map[string]*discordQueue{}
+)
+
+// discordMessage is the body of a webhook message, ThreadName create a
+// thread when the webhook belongs to a forum channel
+type discordMessage struct {
+	Username   string                 `json:"username,omitempty"`
+	Content    string                 `json:"content,omitempty"`
+	Embeds     []discordwebhook.Embed `json:"embeds,omitempty"`
+	ThreadName string                 `json:"thread_name,omitempty"`
+}
+
+// discordRequest is a message waiting in a discordQueue, posted into the
+// ThreadID thread when set. The result of the delivery is sent on result.
+type discordRequest struct {
+	message  discordMessage
+	threadID string
+	result   chan discordResult
+}
+
+// discordResult is the outcome of a discordRequest, ChannelID is the
+// channel of the posted message, i.e. the thread when one was created
+type discordResult struct {
+	channelID string
+	err       error
+}
+
+// merge append req to the message when both fit in a single message
+func (r *discordRequest) merge(req discordRequest) bool {
+	if r.threadID != req.threadID || r.message.ThreadName != "" || req.message.ThreadName != "" {
+		return false
+	}
+	if len(r.message.Embeds)+len(req.message.Embeds) > discordMaxEmbeds ||
+		embedsLength(r.message.Embeds)+embedsLength(req.message.Embeds) > discordMaxEmbedsLength ||
+		len([]rune(r.message.Content))+len([]rune(req.message.Content))+1 > discordMaxContent {
+		return false
+	}
+
+	r.message.Embeds = append(r.message.Embeds, req.message.Embeds...)
+	if r.message.Content != "" && req.message.Content != "" {
+		r.message.Content += "\n"
+	}
+	r.message.Content += req.message.Content
+	return true
+}
+
+// discordQueue send the messages of a webhook one at a time, honouring the
+// Discord rate limit bucket and Retry-After. Messages queued while waiting
+// are coalesced into a single message with an embed list, e.g. the
+// delegations of a block.
+type discordQueue struct {
+	webhook  string
+	requests chan discordRequest
+
+	// next is a request taken from requests which did not fit in the
+	// previous message
+	next *discordRequest
+
+	// remaining messages allowed before resetAt
+	remaining int
+	resetAt   time.Time
+}
+
+// discordQueueFor return the queue of webhook, started on first use
+func discordQueueFor(webhook string) *discordQueue {
+	discordQueuesMu.Lock()
+	defer discordQueuesMu.Unlock()
+
+	q, ok := discordQueues[webhook]
+	if !ok {
+		q = &discordQueue{
+			webhook:   webhook,
+			requests:  make(chan discordRequest, discordQueueSize),
+			remaining: 1,
+		}
+		discordQueues[webhook] = q
+		go q.run()
+	}
+	return q
+}
+
+// send queue message and wait until it is delivered, it return the channel
+// of the posted message
+func (q *discordQueue) send(message discordMessage, threadID string) (string, error) {
+	message.Username = discordUsername
+	req := discordRequest{
+		message:  message,
+		threadID: threadID,
+		result:   make(chan discordResult, 1),
+	}
+
+	select {
+	case q.requests <- req:
+	default:
+		return "", errors.Errorf("discord: queue full (%d messages)", discordQueueSize)
+	}
+	res := <-req.result
+	return res.channelID, res.err
+}
+
+func (q *discordQueue) run() {
+	for {
+		batch := q.batch()
+
+		merged := batch[0]
+		merged.message.Embeds = append([]discordwebhook.Embed(nil), merged.message.Embeds...)
+		for _, req := range batch[1:] {
+			merged.merge(req)
+		}
+		if len(batch) > 1 {
+			logrus.WithField("messages", len(batch)).Info("discord: messages coalesced")
+		}
+
+		channelID, err := q.post(merged.message, merged.threadID)
+		for _, req := range batch {
+			req.result <- discordResult{channelID: channelID, err: err}
+		}
+	}
+}
+
+// batch wait for a request and the rate limit, then append the queued
+// requests fitting in the same message
+func (q *discordQueue) batch() []discordRequest {
+	var first discordRequest
+	if q.next != nil {
+		first, q.next = *q.next, nil
+	} else {
+		first = <-q.requests
+	}
+	// the requests queued meanwhile are coalesced
+	q.wait()
+
+	batch := []discordRequest{first}
+	merged := first
+	merged.message.Embeds = append([]discordwebhook.Embed(nil), first.message.Embeds...)
+	for {
+		select {
+		case req := <-q.requests:
+			if !merged.merge(req) {
+				q.next = &req
+				return batch
+			}
+			batch = append(batch, req)
+		default:
+			return batch
+		}
+	}
+}
+
+// post send the message, waiting for the rate limit and retrying when rate
+// limited, it return the channel of the posted message
+func (q *discordQueue) post(message discordMessage, threadID string) (string, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	u, err := url.Parse(q.webhook)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	query := u.Query()
+	query.Set("wait", "true")
+	if threadID != "" {
+		query.Set("thread_id", threadID)
+	}
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		q.wait()
+
+		channelID, retryAfter, err := q.do(u.String(), payload)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if retryAfter == 0 {
+			return channelID, nil
+		}
+		if attempt == discordMaxRetries {
+			return "", errors.Errorf("discord: still rate limited after %d retries", discordMaxRetries)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"retry_after": retryAfter,
+			"attempt":     attempt + 1,
+		}).Warn("discord: rate limited")
+		time.Sleep(retryAfter)
+	}
+}
+
+// wait until the rate limit bucket allow a message
+func (q *discordQueue) wait() {
+	if q.remaining > 0 {
+		return
+	}
+	if wait := time.Until(q.resetAt); wait > 0 {
+		logrus.WithField("wait", wait).Debug("discord: waiting for rate limit")
+		time.Sleep(wait)
+	}
+}
+
+// do post payload and update the rate limit bucket, it return the channel of
+// the posted message or how long to wait before retrying when rate limited
+func (q *discordQueue) do(url string, payload []byte) (string, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", 0, errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", 0, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	q.remaining = 1
+	if v, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		q.remaining = v
+	}
+	if v, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset-After"), 64); err == nil {
+		q.resetAt = time.Now().Add(seconds(v))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", discordRetryAfter(resp.Header, body), nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", 0, errors.Errorf("%s: %s", resp.Status, string(body))
+	}
+
+	posted := struct {
+		ChannelID string `json:"channel_id"`
+	}{}
+	_ = json.Unmarshal(body, &posted)
+	return posted.ChannelID, 0, nil
+}
+
+// discordRetryAfter read the retry delay of a 429 response, from the JSON
+// body (seconds as a float) or the Retry-After header
+func discordRetryAfter(header http.Header, body []byte) time.Duration {
+	rateLimit := struct {
+		RetryAfter float64 `json:"retry_after"`
+	}{}
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.RetryAfter > 0 {
+		return seconds(rateLimit.RetryAfter)
+	}
+	if v, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && v > 0 {
+		return seconds(v)
+	}
+	return time.Second
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
+
+// embedsLength return the number of characters counted by Discord
+func embedsLength(embeds []discordwebhook.Embed) int {
+	length := 0
+	add := func(s *string) {
+		if s != nil {
+			length += len([]rune(*s))
+		}
+	}
+	for _, e := range embeds {
+		add(e.Title)
+		add(e.Description)
+		if e.Footer != nil {
+			add(e.Footer.Text)
+		}
+		if e.Fields != nil {
+			for _, f := range *e.Fields {
+				add(f.Name)
+				add(f.Value)
+			}
+		}
+	}
+	return length
+}
@@ -0,0 +1,287 @@
+package notifyer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gtuk/discordwebhook"
+)
+
+// discordServer is a webhook answering with a rate limit bucket of one
+// message per resetAfter
+type discordServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	messages []discordMessage
+	// status of the next responses, 200 when empty
+	statuses []int
+	// hold delay the responses until it is closed, when set
+	hold chan struct{}
+}
+
+func newDiscordServer(t *testing.T, resetAfter time.Duration) *discordServer {
+	s := &discordServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := discordMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		if s.hold != nil {
+			<-s.hold
+		}
+
+		s.mu.Lock()
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		if status == http.StatusOK {
+			s.messages = append(s.messages, m)
+		}
+		s.mu.Unlock()
+
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset-After", fmt.Sprint(resetAfter.Seconds()))
+		if status == http.StatusTooManyRequests {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"retry_after": 0.05}`)
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"channel_id": "1"}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *discordServer) list() []discordMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]discordMessage(nil), s.messages...)
+}
+
+// embeds return the number of embeds received
+func (s *discordServer) embeds() int {
+	n := 0
+	for _, m := range s.list() {
+		n += len(m.Embeds)
+	}
+	return n
+}
+
+func TestDiscordCoalesce(t *testing.T) {
+	srv := newDiscordServer(t, 200*time.Millisecond)
+	c := DiscordClient{Webhook: srv.URL}
+
+	// the delegations of several chains at once
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := c.Delegation(DelegationMsg{
+				Event:  Event{Chain: "juno", Kind: KindDelegation, Height: int64(i)},
+				Amount: float64(i),
+				Token:  "JUNO",
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := srv.embeds(); n != 25 {
+		t.Fatalf("%d delegations delivered, want 25", n)
+	}
+	messages := srv.list()
+	// the first delegation is sent right away, the other ones are
+	// coalesced by 10 embeds while waiting for the rate limit
+	if len(messages) > 4 {
+		t.Fatalf("%d messages posted for 25 delegations", len(messages))
+	}
+	for _, m := range messages {
+		if len(m.Embeds) > discordMaxEmbeds || m.Username != discordUsername {
+			t.Fatalf("invalid message: %d embeds, username %q", len(m.Embeds), m.Username)
+		}
+	}
+}
+
+func TestDiscordSendBatch(t *testing.T) {
+	srv := newDiscordServer(t, 0)
+	srv.statuses = []int{http.StatusOK, http.StatusInternalServerError}
+	c := DiscordClient{Webhook: srv.URL}
+
+	msgs := []interface{}{}
+	for i := 0; i < 15; i++ {
+		msgs = append(msgs, DelegationMsg{Event: Event{Chain: "juno", Height: int64(i)}, Amount: 1})
+	}
+
+	// the second message failed, its delegations must be retried
+	n, err := c.sendBatch(msgs)
+	if err == nil || n != discordMaxEmbeds {
+		t.Fatalf("sendBatch = %d, %v, want %d and an error", n, err, discordMaxEmbeds)
+	}
+	if messages := srv.list(); len(messages) != 1 || len(messages[0].Embeds) != discordMaxEmbeds {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+
+	if n, err := c.sendBatch(msgs[n:]); err != nil || n != 5 {
+		t.Fatalf("sendBatch = %d, %v, want 5", n, err)
+	}
+	if !c.batchable(msgInfo) || c.batchable(msgAlert) || (DiscordClient{ThreadsWebhook: srv.URL}).batchable(msgInfo) {
+		t.Fatal("unexpected batchable types")
+	}
+}
+
+func TestDiscordRateLimited(t *testing.T) {
+	srv := newDiscordServer(t, 0)
+	srv.statuses = []int{http.StatusTooManyRequests, http.StatusTooManyRequests}
+
+	q := discordQueueFor(srv.URL)
+	channelID, err := q.send(discordMessage{Content: "hello"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channelID != "1" {
+		t.Fatalf("channel %q", channelID)
+	}
+	if messages := srv.list(); len(messages) != 1 || messages[0].Content != "hello" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+}
+
+func TestDiscordRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		body   string
+		want   time.Duration
+	}{
+		{"", `{"retry_after": 1.5}`, 1500 * time.Millisecond},
+		{"3", `{"message": "You are being rate limited."}`, 3 * time.Second},
+		{"2", `{"retry_after": 0.25}`, 250 * time.Millisecond},
+		{"", "", time.Second},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.header != "" {
+			header.Set("Retry-After", tt.header)
+		}
+		if got := discordRetryAfter(header, []byte(tt.body)); got != tt.want {
+			t.Errorf("header %q body %q: %s, want %s", tt.header, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDiscordMerge(t *testing.T) {
+	embeds := func(n int) []discordwebhook.Embed {
+		return make([]discordwebhook.Embed, n)
+	}
+	tests := []struct {
+		name string
+		a, b discordRequest
+		want bool
+	}{
+		{"embeds", discordRequest{message: discordMessage{Embeds: embeds(4)}}, discordRequest{message: discordMessage{Embeds: embeds(6)}}, true},
+		{"too many embeds", discordRequest{message: discordMessage{Embeds: embeds(4)}}, discordRequest{message: discordMessage{Embeds: embeds(7)}}, false},
+		{"content", discordRequest{message: discordMessage{Content: "a"}}, discordRequest{message: discordMessage{Content: "b"}}, true},
+		{"other thread", discordRequest{threadID: "1"}, discordRequest{threadID: "2"}, false},
+		{"new thread", discordRequest{}, discordRequest{message: discordMessage{ThreadName: "juno"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.merge(tt.b); got != tt.want {
+			t.Errorf("%s: merge = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	r := discordRequest{message: discordMessage{Content: "a"}}
+	r.merge(discordRequest{message: discordMessage{Content: "b"}})
+	if r.message.Content != "a\nb" {
+		t.Errorf("merged content %q", r.message.Content)
+	}
+}
+
+func TestDiscordCoalesceThroughOutbox(t *testing.T) {
+	srv := newDiscordServer(t, 200*time.Millisecond)
+	srv.hold = make(chan struct{})
+	c, err := NewClient(Config{
+		DatabasePath: filepath.Join(t.TempDir(), "test.db"),
+		Destinations: []DestinationConfig{{
+			Name:    "discord",
+			Backend: &DiscordClient{Webhook: srv.URL},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// the delegations of a block
+	for i := 0; i < 25; i++ {
+		c.Delegation(DelegationMsg{Event: Event{Chain: "juno", Height: int64(i)}, Amount: 1})
+	}
+	// the first delegation is being posted, the other ones wait in the outbox
+	close(srv.hold)
+
+	eventually(t, "the delegations", func() bool { return srv.embeds() == 25 && c.outbox.depth() == 0 })
+	if n := len(srv.list()); n > 4 {
+		t.Fatalf("%d messages posted for 25 delegations", n)
+	}
+}
+
+func TestDiscordThreadRecoverFailed(t *testing.T) {
+	srv := newDiscordServer(t, 0)
+	c := DiscordClient{ThreadsWebhook: srv.URL}
+	e := Event{Chain: "juno", Kind: KindJailed}
+
+	if err := c.Alert(AlertMsg{Event: e}); err != nil {
+		t.Fatal(err)
+	}
+	if c.thread(e.Key()) != "1" {
+		t.Fatal("thread not recorded")
+	}
+
+	// the thread is kept until the recovery is posted in it
+	srv.mu.Lock()
+	srv.statuses = []int{http.StatusInternalServerError}
+	srv.mu.Unlock()
+	if err := c.Recover(RecoverMsg{Event: e}); err == nil {
+		t.Fatal("recovery did not fail")
+	}
+	if c.thread(e.Key()) != "1" {
+		t.Fatal("thread closed by a failed recovery")
+	}
+
+	if err := c.Recover(RecoverMsg{Event: e}); err != nil {
+		t.Fatal(err)
+	}
+	if c.thread(e.Key()) != "" {
+		t.Fatal("thread not closed")
+	}
+	if msgs := srv.list(); len(msgs) != 2 || msgs[0].ThreadName == "" || msgs[1].ThreadName != "" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+func TestDiscordThreadPointerFailed(t *testing.T) {
+	threads := newDiscordServer(t, 0)
+	channel := newDiscordServer(t, 0)
+	channel.statuses = []int{http.StatusInternalServerError}
+	c := DiscordClient{Webhook: channel.URL, ThreadsWebhook: threads.URL}
+	e := Event{Chain: "juno", Kind: KindInactive}
+
+	// the alert is in the thread, a failed pointer must not get it retried
+	if err := c.Alert(AlertMsg{Event: e}); err != nil {
+		t.Fatal(err)
+	}
+	if len(threads.list()) != 1 || c.thread(e.Key()) != "1" {
+		t.Fatal("thread not opened")
+	}
+}
@@ -0,0 +1,219 @@
+package notifyer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// EmailClient is complient with the Service interface
+//
+// Every notification is mailed to each of To as a multipart/alternative
+// message carrying plain-text and HTML bodies.
+type EmailClient struct {
+	// Host is the SMTP server address as host:port
+	Host     string   `yaml:"host"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+
+	// SubjectPrefix is prepended to every subject, default to "[cosmos-notifyer]"
+	SubjectPrefix string `yaml:"subject_prefix"`
+	// ImplicitTLS connect using TLS from the start (usually port 465),
+	// otherwise STARTTLS is used when the server support it.
+	ImplicitTLS bool `yaml:"implicit_tls"`
+}
+
+func (c EmailClient) validate() error {
+	if c.Host == "" || c.From == "" || len(c.To) == 0 {
+		return errors.New("email: host, from and to are required")
+	}
+	return nil
+}
+
+func (c EmailClient) subject(s string) string {
+	prefix := c.SubjectPrefix
+	if prefix == "" {
+		prefix = "[cosmos-notifyer]"
+	}
+	return prefix + " " + s
+}
+
+// buildMessage return a RFC 5322 message with plain-text and HTML alternatives
+func (c EmailClient) buildMessage(subject string, text string) ([]byte, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", fmt.Sprintf(
+			"<html><body><p>%s</p><p><small>cosmos-notifyer</small></p></body></html>",
+			strings.ReplaceAll(html.EscapeString(text), "\n", "<br>"))},
+	}
+	for _, part := range parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "Message-ID: <%s@cosmos-notifyer>\r\n", hex.EncodeToString(id))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%s\r\n", w.Boundary())
+	fmt.Fprintf(msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (c EmailClient) dial() (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(c.Host)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if c.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.Host, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", c.Host)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Trace(err)
+	}
+
+	if !c.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, host)); err != nil {
+			client.Close()
+			return nil, errors.Trace(err)
+		}
+	}
+	return client, nil
+}
+
+func (c EmailClient) send(subject string, text string) error {
+	msg, err := c.buildMessage(c.subject(subject), text)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	client, err := c.dial()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer client.Close()
+
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return errors.Trace(err)
+	}
+	for _, to := range c.To {
+		if err := client.Rcpt(to); err != nil {
+			return errors.Annotatef(err, "recipient %s", to)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(client.Quit())
+}
+
+// body return the text followed by the event details
+func (c EmailClient) body(text string, e Event) string {
+	b := strings.Builder{}
+	b.WriteString(text)
+	b.WriteString("\n")
+	for _, d := range e.details() {
+		fmt.Fprintf(&b, "\n%s: %s", d.Label, d.Value)
+	}
+	return b.String()
+}
+
+func (c EmailClient) Alert(msg AlertMsg) error {
+	return c.send(strings.ToUpper(string(msg.Severity))+": "+msg.Msg, c.body(msg.Msg, msg.Event))
+}
+
+func (c EmailClient) Recover(msg RecoverMsg) error {
+	return c.send("RECOVERED: "+msg.Msg, c.body(msg.Msg, msg.Event))
+}
+
+func (c EmailClient) Delegation(msg DelegationMsg) error {
+	return c.send(msg.Msg, c.body(msg.Msg, msg.Event))
+}
+
+func (c EmailClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(msg.Msg, c.body(msg.Msg, msg.Event))
+}
+
+func (c EmailClient) Info(msg InfoMsg) error {
+	return c.send(msg.Title, c.body(msg.Msg, msg.Event))
+}
@@ -0,0 +1,81 @@
+package notifyer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestEmailBuildMessage(t *testing.T) {
+	c := EmailClient{From: "notifyer@example.com", To: []string{"ops@example.com", "oncall@example.com"}}
+	subject := "[cosmos-notifyer] juno: validateur emprisonné"
+	text := "validator <b>jailed</b>\nmissed 500 blocks"
+
+	data, err := c.buildMessage(subject, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := m.Header.Get("Subject")
+	if !strings.HasPrefix(raw, "=?UTF-8?q?") {
+		t.Fatalf("subject %q is not Q-encoded", raw)
+	}
+	if got, err := new(mime.WordDecoder).DecodeHeader(raw); err != nil || got != subject {
+		t.Fatalf("decoded subject %q, %v", got, err)
+	}
+	if to := m.Header.Get("To"); to != "ops@example.com, oncall@example.com" {
+		t.Fatalf("to %q", to)
+	}
+	if m.Header.Get("Message-ID") == "" || m.Header.Get("MIME-Version") != "1.0" {
+		t.Fatalf("missing headers: %v", m.Header)
+	}
+	if _, err := m.Header.Date(); err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q, %v", mediaType, err)
+	}
+
+	// the reader decode the quoted-printable parts
+	parts := map[string]string{}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[contentType] = string(body)
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("parts %v, want text/plain and text/html", parts)
+	}
+	// quoted-printable text use CRLF line breaks
+	if parts["text/plain"] != strings.ReplaceAll(text, "\n", "\r\n") {
+		t.Fatalf("text part %q", parts["text/plain"])
+	}
+	if html := parts["text/html"]; !strings.Contains(html, "validator &lt;b&gt;jailed&lt;/b&gt;<br>missed 500 blocks") {
+		t.Fatalf("html part %q", html)
+	}
+}
@@ -0,0 +1,176 @@
+package notifyer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventKind identify what a notification is about
+type EventKind string
+
+const (
+	KindRPCDown      EventKind = "rpc-down"
+	KindJailed       EventKind = "jailed"
+	KindTombstoned   EventKind = "tombstoned"
+	KindInactive     EventKind = "inactive"
+	KindMissedBlocks EventKind = "missed-blocks"
+	KindDelegation   EventKind = "delegation"
+	KindUnDelegation EventKind = "undelegation"
+
+	// KindFlapping is sent when a condition is flapping, its "condition"
+	// field hold the kind of the flapping condition
+	KindFlapping EventKind = "flapping"
+
+	// KindSilenceEnded is the info sent when a silence ends
+	KindSilenceEnded EventKind = "silence-ended"
+	// KindDigest is the periodic report of a chain
+	KindDigest EventKind = "digest"
+	// KindExternal is an inbound alert without a kind
+	KindExternal EventKind = "external"
+)
+
+// Severity of an event, from SeverityInfo to SeverityCritical
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Level return a comparable level of the severity, unknown severities are info
+func (s Severity) Level() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// DefaultSeverity return the severity used for kind when an event doesn't set one
+func DefaultSeverity(kind EventKind) Severity {
+	switch kind {
+	case KindJailed, KindTombstoned, KindInactive, KindMissedBlocks:
+		return SeverityCritical
+	case KindRPCDown, KindFlapping, KindExternal:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// Event is the structured data carried by every message
+type Event struct {
+	Kind     EventKind
+	Severity Severity
+
+	Chain     string
+	Moniker   string
+	Validator string
+	Height    int64
+
+	// ID distinguish the conditions sharing a chain and a kind, e.g. the
+	// fingerprint of an inbound Alertmanager alert
+	ID string
+
+	Time time.Time
+
+	// ExplorerURL link the event on a block explorer, see Explorer
+	ExplorerURL string
+
+	// Fields hold arbitrary data about the event, e.g. missed_blocks
+	Fields map[string]string
+}
+
+// Key return the condition key shared by an alert and its recovery, e.g. juno/jailed
+func (e Event) Key() string {
+	key := e.Chain + "/" + string(e.Kind)
+	if e.Kind == KindFlapping {
+		key += "/" + e.Fields["condition"]
+	}
+	if e.ID != "" {
+		key += "/" + e.ID
+	}
+	return key
+}
+
+// withDefaults fill the kind, severity and time when they are not set
+func (e Event) withDefaults(kind EventKind) Event {
+	if e.Kind == "" {
+		e.Kind = kind
+	}
+	if e.Severity == "" {
+		e.Severity = DefaultSeverity(e.Kind)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	return e
+}
+
+// Explorer hold the block explorer URL patterns of a chain, {validator},
+// {height} and {tx} are replaced by the event values, e.g.
+// https://www.mintscan.io/juno/blocks/{height}
+type Explorer struct {
+	Validator string `yaml:"validator"`
+	Block     string `yaml:"block"`
+	Tx        string `yaml:"tx"`
+}
+
+// URL return the transaction link when the event has a "tx_hash" field, the
+// block link when it has a height and the validator link otherwise
+func (x Explorer) URL(e Event) string {
+	pattern := x.Validator
+	if e.Height != 0 && x.Block != "" {
+		pattern = x.Block
+	}
+	if e.Fields["tx_hash"] != "" && x.Tx != "" {
+		pattern = x.Tx
+	}
+	return strings.NewReplacer(
+		"{validator}", e.Validator,
+		"{height}", strconv.FormatInt(e.Height, 10),
+		"{tx}", e.Fields["tx_hash"],
+	).Replace(pattern)
+}
+
+// eventDetail is a label/value pair describing an event
+type eventDetail struct {
+	Label string
+	Value string
+}
+
+// details return the non empty values of the event, fields are sorted by key
+func (e Event) details() []eventDetail {
+	ret := make([]eventDetail, 0, 6+len(e.Fields))
+
+	add := func(label, value string) {
+		if value != "" {
+			ret = append(ret, eventDetail{Label: label, Value: value})
+		}
+	}
+	add("Chain", e.Chain)
+	add("Kind", string(e.Kind))
+	add("Severity", string(e.Severity))
+	add("Validator", e.Moniker)
+	add("Address", e.Validator)
+	if e.Height != 0 {
+		add("Height", strconv.FormatInt(e.Height, 10))
+	}
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, e.Fields[k])
+	}
+	add("Explorer", e.ExplorerURL)
+	return ret
+}
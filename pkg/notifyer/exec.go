@@ -0,0 +1,122 @@
+package notifyer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const execDefaultTimeout = 30 * time.Second
+
+// ExecClient is complient with the Service interface
+//
+// Command is run for every notification, the event is passed on stdin as a
+// WebhookPayload JSON and as CN_* environment variables (CN_TYPE, CN_KIND,
+// CN_SEVERITY, CN_CHAIN, CN_MSG...). Stderr is forwarded to the logs.
+type ExecClient struct {
+	// Command is the program and its arguments
+	Command []string `yaml:"command"`
+	// Timeout default to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c ExecClient) validate() error {
+	if len(c.Command) == 0 {
+		return errors.New("exec: command is required")
+	}
+	return nil
+}
+
+func (p WebhookPayload) env() []string {
+	env := []string{
+		"CN_VERSION=" + strconv.Itoa(p.Version),
+		"CN_TYPE=" + p.Type,
+		"CN_KIND=" + p.Kind,
+		"CN_KEY=" + p.Key,
+		"CN_SEVERITY=" + p.Severity,
+		"CN_CHAIN=" + p.Chain,
+		"CN_VALIDATOR=" + p.Validator,
+		"CN_MONIKER=" + p.Moniker,
+		"CN_HEIGHT=" + strconv.FormatInt(p.Height, 10),
+		"CN_AMOUNT=" + strconv.FormatFloat(p.Amount, 'f', -1, 64),
+		"CN_TOKEN=" + p.Token,
+		"CN_TITLE=" + p.Title,
+		"CN_MSG=" + p.Msg,
+		"CN_TIMESTAMP=" + p.Timestamp.Format(time.RFC3339),
+	}
+	for k, v := range p.Fields {
+		env = append(env, "CN_FIELD_"+strings.ToUpper(k)+"="+v)
+	}
+	return env
+}
+
+func (c ExecClient) run(p WebhookPayload) error {
+	if len(c.Command) == 0 {
+		return errors.New("exec: no command configured")
+	}
+
+	p.Version = WebhookPayloadVersion
+
+	stdin, err := json.Marshal(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = execDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), p.env()...)
+
+	err = cmd.Run()
+
+	if stderr.Len() > 0 {
+		logrus.WithFields(logrus.Fields{
+			"command": c.Command[0],
+			"type":    p.Type,
+			"chain":   p.Chain,
+		}).Warn(strings.TrimSpace(stderr.String()))
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("exec %s: timeout after %s", c.Command[0], timeout)
+	}
+	if err != nil {
+		return errors.Annotatef(err, "exec %s", c.Command[0])
+	}
+	return nil
+}
+
+func (c ExecClient) Alert(msg AlertMsg) error {
+	return c.run(alertPayload(msg))
+}
+
+func (c ExecClient) Recover(msg RecoverMsg) error {
+	return c.run(recoverPayload(msg))
+}
+
+func (c ExecClient) Delegation(msg DelegationMsg) error {
+	return c.run(delegationPayload(msg))
+}
+
+func (c ExecClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.run(unDelegationPayload(msg))
+}
+
+func (c ExecClient) Info(msg InfoMsg) error {
+	return c.run(infoPayload(msg))
+}
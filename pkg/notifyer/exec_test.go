@@ -0,0 +1,97 @@
+package notifyer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestExecClient(t *testing.T) {
+	dir := t.TempDir()
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	// the script save its stdin and CN_* environment, given as $1
+	c := ExecClient{Command: []string{"sh", "-c", `cat > "$1/stdin.json"; env | grep ^CN_ > "$1/env"; echo "missing $CN_CHAIN runbook" >&2`, "sh", dir}}
+	err := c.Alert(AlertMsg{
+		Event: Event{
+			Kind:     KindJailed,
+			Severity: SeverityCritical,
+			Chain:    "juno",
+			Moniker:  "nysa",
+			Height:   1234,
+			Fields:   map[string]string{"missed_blocks": "500"},
+		},
+		Msg: "validator jailed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := WebhookPayload{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != WebhookPayloadVersion || p.Type != "alert" || p.Kind != string(KindJailed) || p.Chain != "juno" ||
+		p.Height != 1234 || p.Msg != "validator jailed" || p.Fields["missed_blocks"] != "500" {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Split(strings.TrimSpace(string(data)), "\n")
+	sort.Strings(env)
+	for _, want := range []string{
+		"CN_CHAIN=juno",
+		"CN_FIELD_MISSED_BLOCKS=500",
+		"CN_HEIGHT=1234",
+		"CN_KIND=" + string(KindJailed),
+		"CN_MONIKER=nysa",
+		"CN_MSG=validator jailed",
+		"CN_SEVERITY=" + string(SeverityCritical),
+		"CN_TYPE=alert",
+	} {
+		i := sort.SearchStrings(env, want)
+		if i == len(env) || env[i] != want {
+			t.Errorf("%s not in the environment %v", want, env)
+		}
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel || entry.Message != "missing juno runbook" || entry.Data["command"] != "sh" {
+		t.Fatalf("stderr not logged: %+v", entry)
+	}
+}
+
+func TestExecClientFailed(t *testing.T) {
+	c := ExecClient{Command: []string{"sh", "-c", "exit 3"}}
+	if err := c.Info(InfoMsg{}); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("error %v, want the exit status", err)
+	}
+}
+
+func TestExecClientTimeout(t *testing.T) {
+	c := ExecClient{Command: []string{"sh", "-c", "exec sleep 5"}, Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	err := c.Alert(AlertMsg{Event: Event{Chain: "juno", Kind: KindJailed}})
+	if err == nil || !strings.Contains(err.Error(), "timeout after 100ms") {
+		t.Fatalf("error %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("command killed after %s", d)
+	}
+}
@@ -0,0 +1,57 @@
+package notifyer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// postJSON send body encoded as JSON to url and fail on non 2xx responses
+func postJSON(url string, body interface{}, headers map[string]string) error {
+	return sendJSON(http.MethodPost, url, body, headers)
+}
+
+// sendJSON send body encoded as JSON to url using method and fail on non 2xx responses
+func sendJSON(method string, url string, body interface{}, headers map[string]string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return send(method, url, "application/json", payload, headers)
+}
+
+// post send payload to url and fail on non 2xx responses
+func post(url string, contentType string, payload []byte, headers map[string]string) error {
+	return send(http.MethodPost, url, contentType, payload, headers)
+}
+
+func send(method string, url string, contentType string, payload []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("%s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package notifyer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// AlertmanagerWebhook is the payload of the Alertmanager webhook receiver
+type AlertmanagerWebhook struct {
+	Version  string                     `json:"version"`
+	Status   string                     `json:"status"`
+	Receiver string                     `json:"receiver"`
+	Alerts   []AlertmanagerWebhookAlert `json:"alerts"`
+}
+
+// AlertmanagerWebhookAlert is an alert of an AlertmanagerWebhook
+type AlertmanagerWebhookAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// TenderdutyAlert is a tenderduty-style alert
+type TenderdutyAlert struct {
+	Chain     string `json:"chain"`
+	Validator string `json:"validator"`
+	Moniker   string `json:"moniker"`
+	// Kind of the alert, KindExternal when empty
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Resolved bool   `json:"resolved"`
+	// Key identify the alert among the alerts of the chain and kind,
+	// the resolution must carry the same key
+	Key string `json:"key"`
+}
+
+// ParseSeverity map the severities used by other monitors to a Severity,
+// unknown severities are warning
+func ParseSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "critical", "error", "page", "high", "p1", "p2":
+		return SeverityCritical
+	case "info", "information", "informational", "low", "none", "p5":
+		return SeverityInfo
+	default:
+		return SeverityWarning
+	}
+}
+
+// alertmanagerLabels are the labels mapped to the Event fields
+var alertmanagerLabels = []string{"alertname", "chain", "validator", "moniker", "condition", "severity"}
+
+// event return the Event of an inbound Alertmanager alert. The kind is the
+// "condition" label or the alert name, the chain, validator and moniker
+// come from the labels of the same name and the other labels are fields.
+func (a AlertmanagerWebhookAlert) event() Event {
+	kind := a.Labels["condition"]
+	if kind == "" {
+		kind = a.Labels["alertname"]
+	}
+	if kind == "" {
+		kind = string(KindExternal)
+	}
+
+	e := Event{
+		Kind:      EventKind(kind),
+		Severity:  ParseSeverity(a.Labels["severity"]),
+		Chain:     a.Labels["chain"],
+		Validator: a.Labels["validator"],
+		Moniker:   a.Labels["moniker"],
+		ID:        a.Fingerprint,
+		Fields: map[string]string{
+			"source": "alertmanager",
+		},
+	}
+	for k, v := range a.Labels {
+		if !contains(alertmanagerLabels, k) {
+			e.Fields[k] = v
+		}
+	}
+	if a.GeneratorURL != "" {
+		e.Fields["generator_url"] = a.GeneratorURL
+	}
+	return e
+}
+
+// msg return the summary of the alert
+func (a AlertmanagerWebhookAlert) msg() string {
+	for _, k := range []string{"summary", "description", "message"} {
+		if v := a.Annotations[k]; v != "" {
+			return v
+		}
+	}
+	return a.Labels["alertname"]
+}
+
+// validate return an error when the alert status is unknown
+func (a AlertmanagerWebhookAlert) validate() error {
+	if a.Status != "firing" && a.Status != "resolved" {
+		return errors.Errorf("alertmanager: unknown alert status: %q", a.Status)
+	}
+	return nil
+}
+
+// ReceiveAlertmanager notify the alerts of an Alertmanager webhook, firing
+// alerts are sent as alerts and resolved ones as recoveries. The payload is
+// rejected as a whole when an alert is invalid, before anything is sent, so
+// that its retry by Alertmanager does not send the valid alerts twice.
+func (c Client) ReceiveAlertmanager(payload AlertmanagerWebhook) error {
+	for i, a := range payload.Alerts {
+		if err := a.validate(); err != nil {
+			return errors.Annotatef(err, "alert #%d", i)
+		}
+	}
+
+	var errs error
+	for _, a := range payload.Alerts {
+		var err error
+		if a.Status == "resolved" {
+			err = c.Recover(RecoverMsg{Event: a.event(), Msg: a.msg()})
+		} else {
+			err = c.Alert(AlertMsg{Event: a.event(), Msg: a.msg()})
+		}
+		if err != nil {
+			errs = errors.Wrap(errs, err)
+		}
+	}
+	return errs
+}
+
+// validate return an error when a required field is missing
+func (a TenderdutyAlert) validate() error {
+	if a.Chain == "" || a.Message == "" {
+		return errors.New("tenderduty: chain and message are required")
+	}
+	return nil
+}
+
+// event return the Event of a tenderduty-style alert
+func (a TenderdutyAlert) event() Event {
+	kind := EventKind(a.Kind)
+	if kind == "" {
+		kind = KindExternal
+	}
+	return Event{
+		Kind:      kind,
+		Severity:  ParseSeverity(a.Severity),
+		Chain:     a.Chain,
+		Validator: a.Validator,
+		Moniker:   a.Moniker,
+		ID:        a.Key,
+		Fields: map[string]string{
+			"source": "tenderduty",
+		},
+	}
+}
+
+// ReceiveTenderduty notify tenderduty-style alerts, as recoveries when
+// resolved. The alerts are rejected as a whole when one is invalid, before
+// anything is sent.
+func (c Client) ReceiveTenderduty(alerts ...TenderdutyAlert) error {
+	for i, a := range alerts {
+		if err := a.validate(); err != nil {
+			return errors.Annotatef(err, "alert #%d", i)
+		}
+	}
+
+	var errs error
+	for _, a := range alerts {
+		var err error
+		if a.Resolved {
+			err = c.Recover(RecoverMsg{Event: a.event(), Msg: a.Message})
+		} else {
+			err = c.Alert(AlertMsg{Event: a.event(), Msg: a.Message})
+		}
+		if err != nil {
+			errs = errors.Wrap(errs, err)
+		}
+	}
+	return errs
+}
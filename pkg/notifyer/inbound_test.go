@@ -0,0 +1,172 @@
+package notifyer
+
+import (
+	"sync"
+	"testing"
+)
+
+// capture is a Backend keeping the messages it receive
+type capture struct {
+	mu   sync.Mutex
+	msgs []interface{}
+}
+
+func (c *capture) add(msg interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *capture) list() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]interface{}(nil), c.msgs...)
+}
+
+func (c *capture) Alert(msg AlertMsg) error {
+	return c.add(msg)
+}
+
+func (c *capture) Recover(msg RecoverMsg) error {
+	return c.add(msg)
+}
+
+func (c *capture) Delegation(msg DelegationMsg) error {
+	return c.add(msg)
+}
+
+func (c *capture) UnDelegation(msg UnDelegationMsg) error {
+	return c.add(msg)
+}
+
+func (c *capture) Info(msg InfoMsg) error {
+	return c.add(msg)
+}
+
+func captureClient(t *testing.T) (*Client, *capture) {
+	out := &capture{}
+	c, err := NewClient(Config{
+		Destinations: []DestinationConfig{{Name: "capture", Backend: out}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, out
+}
+
+func TestReceiveAlertmanager(t *testing.T) {
+	c, out := captureClient(t)
+
+	alert := AlertmanagerWebhookAlert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname": "ValidatorJailed",
+			"condition": "jailed",
+			"severity":  "page",
+			"chain":     "juno",
+			"validator": "junovaloper1xxx",
+			"moniker":   "nysa",
+			"team":      "ops",
+		},
+		Annotations:  map[string]string{"summary": "validator jailed for downtime"},
+		GeneratorURL: "http://prometheus/graph",
+		Fingerprint:  "3f2a",
+	}
+	resolved := alert
+	resolved.Status = "resolved"
+	if err := c.ReceiveAlertmanager(AlertmanagerWebhook{Alerts: []AlertmanagerWebhookAlert{alert}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReceiveAlertmanager(AlertmanagerWebhook{Alerts: []AlertmanagerWebhookAlert{resolved}}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := out.list()
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages sent, want 2", len(msgs))
+	}
+	a, ok := msgs[0].(AlertMsg)
+	if !ok {
+		t.Fatalf("first message is a %T", msgs[0])
+	}
+	if a.Msg != "validator jailed for downtime" {
+		t.Errorf("alert message %q", a.Msg)
+	}
+	if a.Kind != KindJailed || a.Severity != SeverityCritical || a.ID != "3f2a" ||
+		a.Chain != "juno" || a.Validator != "junovaloper1xxx" || a.Moniker != "nysa" {
+		t.Errorf("unexpected event: %+v", a.Event)
+	}
+	if a.Fields["team"] != "ops" || a.Fields["source"] != "alertmanager" ||
+		a.Fields["generator_url"] != "http://prometheus/graph" || a.Fields["alertname"] != "" {
+		t.Errorf("unexpected fields: %v", a.Fields)
+	}
+
+	r, ok := msgs[1].(RecoverMsg)
+	if !ok {
+		t.Fatalf("second message is a %T", msgs[1])
+	}
+	if r.Msg != "validator jailed for downtime" || r.Kind != KindJailed || r.ID != "3f2a" {
+		t.Errorf("unexpected recovery: %+v", r)
+	}
+}
+
+func TestReceiveTenderduty(t *testing.T) {
+	c, out := captureClient(t)
+
+	err := c.ReceiveTenderduty(
+		TenderdutyAlert{Chain: "juno", Moniker: "nysa", Kind: "missed-blocks", Severity: "warning", Message: "missed 10 of the last 100 blocks", Key: "k1"},
+		TenderdutyAlert{Chain: "osmosis", Message: "rpc node is lagging"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.ReceiveTenderduty(TenderdutyAlert{Chain: "juno", Kind: "missed-blocks", Message: "signing again", Key: "k1", Resolved: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := out.list()
+	if len(msgs) != 3 {
+		t.Fatalf("%d messages sent, want 3", len(msgs))
+	}
+	a := msgs[0].(AlertMsg)
+	if a.Msg != "missed 10 of the last 100 blocks" {
+		t.Errorf("alert message %q", a.Msg)
+	}
+	if a.Kind != KindMissedBlocks || a.Severity != SeverityWarning || a.ID != "k1" || a.Moniker != "nysa" ||
+		a.Fields["source"] != "tenderduty" {
+		t.Errorf("unexpected event: %+v", a.Event)
+	}
+	if a := msgs[1].(AlertMsg); a.Kind != KindExternal || a.Msg != "rpc node is lagging" {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+	if r := msgs[2].(RecoverMsg); r.Kind != KindMissedBlocks || r.ID != "k1" || r.Msg != "signing again" {
+		t.Errorf("unexpected recovery: %+v", r)
+	}
+}
+
+func TestReceiveInvalidBatch(t *testing.T) {
+	c, out := captureClient(t)
+
+	err := c.ReceiveAlertmanager(AlertmanagerWebhook{Alerts: []AlertmanagerWebhookAlert{
+		{Status: "firing", Labels: map[string]string{"alertname": "A", "chain": "juno"}},
+		{Status: "pending", Labels: map[string]string{"alertname": "B", "chain": "juno"}},
+	}})
+	if err == nil {
+		t.Fatal("unknown status accepted")
+	}
+
+	err = c.ReceiveTenderduty(
+		TenderdutyAlert{Chain: "juno", Message: "valid"},
+		TenderdutyAlert{Chain: "juno"},
+	)
+	if err == nil {
+		t.Fatal("alert without message accepted")
+	}
+
+	if msgs := out.list(); len(msgs) != 0 {
+		t.Fatalf("invalid batches sent %d messages", len(msgs))
+	}
+}
@@ -0,0 +1,310 @@
+package notifyer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const escalationCheckInterval = 30 * time.Second
+
+var incidentsBucket = []byte("incidents")
+
+// Escalation send the alerts matched by its Route, still unresolved After
+// they were first notified, to the Route destinations. Follow-up alerts and
+// the recovery of an escalated incident are sent there too.
+type Escalation struct {
+	Route `yaml:",inline"`
+
+	After time.Duration `yaml:"after"`
+}
+
+// key identify the escalation among the configured ones, it does not depend
+// on their order so that the escalations of a restored incident are kept when
+// the config is reordered
+func (esc Escalation) key() string {
+	kinds := make([]string, 0, len(esc.Kinds))
+	for _, k := range esc.Kinds {
+		kinds = append(kinds, string(k))
+	}
+	severities := make([]string, 0, len(esc.Severities))
+	for _, s := range esc.Severities {
+		severities = append(severities, string(s))
+	}
+	return fmt.Sprintf("%s chains=%s kinds=%s severities=%s destinations=%s", esc.After,
+		strings.Join(esc.Chains, ","), strings.Join(kinds, ","),
+		strings.Join(severities, ","), strings.Join(esc.Destinations, ","))
+}
+
+// incident is an alerted condition not recovered yet
+type incident struct {
+	Alert    AlertMsg
+	OpenedAt time.Time
+	// Notified is true once an alert of the incident has been sent, i.e. not
+	// silenced
+	Notified bool
+	// SentAt is the latest time the alert was sent, refreshes included
+	SentAt time.Time
+	// Escalations are the keys of the escalations already triggered
+	Escalations []string
+}
+
+// escalation return the alert sent when inc is escalated
+func (inc incident) escalation(now time.Time) AlertMsg {
+	msg := inc.Alert
+
+	fields := map[string]string{}
+	for k, v := range msg.Fields {
+		fields[k] = v
+	}
+	fields["unresolved_for"] = humanizeDuration(now.Sub(inc.OpenedAt))
+	msg.Fields = fields
+	return msg
+}
+
+// refresher is implemented by the backends whose alerts expire unless they
+// are sent again while the incident is open, e.g. alertmanager
+type refresher interface {
+	refreshInterval() time.Duration
+}
+
+// incidents track the open incidents by Event.Key, escalate them and
+// refresh their alert, incidents are persisted in the database when enabled
+// so escalation timers survive a restart.
+type incidents struct {
+	db          *bolt.DB
+	escalations []Escalation
+	// escalate send msg to the destinations of esc, it return false when
+	// the escalation must be retried later
+	escalate func(msg AlertMsg, esc Escalation) bool
+	// refresh send the alert of an open incident again every
+	// refreshInterval, with the escalation destinations of the incident
+	refreshInterval time.Duration
+	refresh         func(msg AlertMsg, escalated []string)
+
+	mu   sync.Mutex
+	open map[string]*incident
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newIncidents(db *bolt.DB, escalations []Escalation, escalate func(msg AlertMsg, esc Escalation) bool,
+	refreshInterval time.Duration, refresh func(msg AlertMsg, escalated []string)) (*incidents, error) {
+	in := &incidents{
+		db:              db,
+		escalations:     escalations,
+		escalate:        escalate,
+		refreshInterval: refreshInterval,
+		refresh:         refresh,
+		open:            map[string]*incident{},
+		stop:            make(chan struct{}),
+	}
+
+	if db != nil {
+		err := db.View(func(tx *bolt.Tx) error {
+			return tx.Bucket(incidentsBucket).ForEach(func(k, v []byte) error {
+				inc := &incident{}
+				if err := json.Unmarshal(v, inc); err != nil {
+					logrus.WithError(err).Error("invalid incident in database")
+					return nil
+				}
+				in.open[string(k)] = inc
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(in.open) > 0 {
+			logrus.WithField("incidents", len(in.open)).Info("open incidents restored")
+		}
+	}
+
+	if len(escalations) > 0 || refreshInterval > 0 {
+		in.wg.Add(1)
+		go in.run()
+	}
+	return in, nil
+}
+
+func (in *incidents) Close() {
+	close(in.stop)
+	in.wg.Wait()
+}
+
+// unresolved return true when an incident is open for key
+func (in *incidents) unresolved(key string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	_, ok := in.open[key]
+	return ok
+}
+
+// track open and close incidents from the alerts and recoveries, it return
+// the escalation destinations the message must also be sent to and whether
+// an alert of the incident has been sent
+func (in *incidents) track(typ msgType, msg interface{}) ([]string, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	switch m := msg.(type) {
+	case AlertMsg:
+		key := m.Key()
+		inc, ok := in.open[key]
+		if !ok {
+			inc = &incident{OpenedAt: m.Time}
+			in.open[key] = inc
+		}
+		inc.Alert = m
+		in.save(key, inc)
+		return in.destinations(inc), inc.Notified
+	case RecoverMsg:
+		key := m.Key()
+		inc, ok := in.open[key]
+		if !ok {
+			return nil, false
+		}
+		delete(in.open, key)
+		in.delete(key)
+		return in.destinations(inc), inc.Notified
+	}
+	return nil, false
+}
+
+// notified record that an alert of the incident key has been sent
+func (in *incidents) notified(key string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if inc, ok := in.open[key]; ok {
+		inc.Notified = true
+		inc.SentAt = time.Now()
+		in.save(key, inc)
+	}
+}
+
+// destinations return the destinations of the escalations triggered for inc
+func (in *incidents) destinations(inc *incident) []string {
+	ret := []string{}
+	for _, esc := range in.escalations {
+		if !contains(inc.Escalations, esc.key()) {
+			continue
+		}
+		for _, name := range esc.Destinations {
+			if !contains(ret, name) {
+				ret = append(ret, name)
+			}
+		}
+	}
+	return ret
+}
+
+func (in *incidents) run() {
+	defer in.wg.Done()
+
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		in.check()
+
+		select {
+		case <-in.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check trigger the escalations and the refreshes due for every open incident
+func (in *incidents) check() {
+	type due struct {
+		key string
+		msg AlertMsg
+		idx int
+	}
+	type stale struct {
+		key       string
+		msg       AlertMsg
+		escalated []string
+	}
+
+	now := time.Now()
+	in.mu.Lock()
+	todo := []due{}
+	refresh := []stale{}
+	for key, inc := range in.open {
+		if in.refreshInterval > 0 && inc.Notified && now.Sub(inc.SentAt) >= in.refreshInterval {
+			refresh = append(refresh, stale{key: key, msg: inc.Alert, escalated: in.destinations(inc)})
+		}
+		for i, esc := range in.escalations {
+			if contains(inc.Escalations, esc.key()) || !esc.Match(inc.Alert.Event) || now.Sub(inc.OpenedAt) < esc.After {
+				continue
+			}
+			todo = append(todo, due{key: key, msg: inc.escalation(now), idx: i})
+		}
+	}
+	in.mu.Unlock()
+
+	for _, d := range todo {
+		if !in.escalate(d.msg, in.escalations[d.idx]) {
+			continue
+		}
+
+		in.mu.Lock()
+		if inc, ok := in.open[d.key]; ok {
+			inc.Escalations = append(inc.Escalations, in.escalations[d.idx].key())
+			in.save(d.key, inc)
+		}
+		in.mu.Unlock()
+	}
+
+	for _, r := range refresh {
+		in.refresh(r.msg, r.escalated)
+
+		in.mu.Lock()
+		if inc, ok := in.open[r.key]; ok {
+			inc.SentAt = now
+			in.save(r.key, inc)
+		}
+		in.mu.Unlock()
+	}
+}
+
+// save persist inc, must be called with the lock held
+func (in *incidents) save(key string, inc *incident) {
+	if in.db == nil {
+		return
+	}
+	err := in.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(inc)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(incidentsBucket).Put([]byte(key), data)
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("incident", key).Error("failed to save incident")
+	}
+}
+
+// delete remove a persisted incident, must be called with the lock held
+func (in *incidents) delete(key string) {
+	if in.db == nil {
+		return
+	}
+	err := in.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(incidentsBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("incident", key).Error("failed to delete incident")
+	}
+}
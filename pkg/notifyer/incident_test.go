@@ -0,0 +1,151 @@
+package notifyer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEscalationKey(t *testing.T) {
+	a := Escalation{Route: Route{Kinds: []EventKind{KindJailed}, Destinations: []string{"oncall"}}, After: time.Hour}
+	b := a
+	b.After = 2 * time.Hour
+	if a.key() == b.key() {
+		t.Fatal("escalations with another delay share a key")
+	}
+	if a.key() != (Escalation{Route: Route{Kinds: []EventKind{KindJailed}, Destinations: []string{"oncall"}}, After: time.Hour}).key() {
+		t.Fatal("key is not stable")
+	}
+}
+
+func TestIncidentsTrack(t *testing.T) {
+	in, err := newIncidents(nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	e := Event{Chain: "juno", Kind: KindJailed}
+	if _, notified := in.track(msgAlert, AlertMsg{Event: e}); notified {
+		t.Fatal("new incident notified")
+	}
+	if !in.unresolved(e.Key()) {
+		t.Fatal("incident not open")
+	}
+	in.notified(e.Key())
+	if _, notified := in.track(msgAlert, AlertMsg{Event: e}); !notified {
+		t.Fatal("follow-up of a notified incident not notified")
+	}
+	if _, notified := in.track(msgRecover, RecoverMsg{Event: e}); !notified {
+		t.Fatal("recovery of a notified incident not notified")
+	}
+	if in.unresolved(e.Key()) {
+		t.Fatal("incident still open after its recovery")
+	}
+	if _, notified := in.track(msgRecover, RecoverMsg{Event: e}); notified {
+		t.Fatal("recovery without incident notified")
+	}
+}
+
+// escalationClient return a client sending every event to "team", the
+// alerts still open after an hour are escalated to "oncall"
+func escalationClient(t *testing.T, path string, team, oncall *recorder, escalations ...Escalation) *Client {
+	escalations = append(escalations, Escalation{
+		Route: Route{Kinds: []EventKind{KindJailed}, Destinations: []string{"oncall"}},
+		After: time.Hour,
+	})
+	c, err := NewClient(Config{
+		DatabasePath: path,
+		Destinations: []DestinationConfig{
+			{Name: "team", Backend: team},
+			{Name: "oncall", Backend: oncall},
+			{Name: "other", Backend: &recorder{}},
+		},
+		Routes:      []Route{{Destinations: []string{"team"}}},
+		Escalations: escalations,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// age make the incident of e open for an hour and save it
+func age(c *Client, e Event) {
+	c.incidents.mu.Lock()
+	defer c.incidents.mu.Unlock()
+
+	inc := c.incidents.open[e.Key()]
+	inc.OpenedAt = time.Now().Add(-time.Hour)
+	c.incidents.save(e.Key(), inc)
+}
+
+func TestEscalationRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	team, oncall := &recorder{}, &recorder{}
+	e := Event{Chain: "juno", Kind: KindJailed}
+
+	c := escalationClient(t, path, team, oncall)
+	c.Alert(AlertMsg{Event: e})
+	eventually(t, "the alert", func() bool { return len(team.list()) == 1 })
+	age(c, e)
+	c.Close()
+
+	// restarted with another escalation before the jailed one
+	c = escalationClient(t, path, team, oncall, Escalation{
+		Route: Route{Kinds: []EventKind{KindInactive}, Destinations: []string{"other"}},
+		After: time.Minute,
+	})
+	defer c.Close()
+	if !c.Unresolved("juno", KindJailed) {
+		t.Fatal("incident not restored")
+	}
+
+	// escalated by the first check, on start
+	eventually(t, "the escalation", func() bool { return len(oncall.list()) == 1 })
+	c.incidents.mu.Lock()
+	escalations := c.incidents.open[e.Key()].Escalations
+	c.incidents.mu.Unlock()
+	if len(escalations) != 1 || escalations[0] != c.cfg.Escalations[1].key() {
+		t.Fatalf("escalations %v, want the jailed one", escalations)
+	}
+
+	// escalated once, the recovery follows the escalation
+	c.incidents.check()
+	c.Recover(RecoverMsg{Event: e})
+	eventually(t, "the recovery", func() bool { return len(oncall.list()) == 2 })
+	if got, want := oncall.list(), []string{"alert:jailed", "recover:jailed"}; !equal(got, want) {
+		t.Fatalf("oncall received %v, want %v", got, want)
+	}
+	if got, want := team.list(), []string{"alert:jailed", "recover:jailed"}; !equal(got, want) {
+		t.Fatalf("team received %v, want %v", got, want)
+	}
+}
+
+func TestEscalationSilenced(t *testing.T) {
+	team, oncall := &recorder{}, &recorder{}
+	e := Event{Chain: "juno", Kind: KindJailed}
+
+	c := escalationClient(t, "", team, oncall)
+	defer c.Close()
+	c.Alert(AlertMsg{Event: e})
+	age(c, e)
+
+	silence, err := c.AddSilence(Silence{Chains: []string{"juno"}, EndsAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.incidents.check()
+	if len(oncall.list()) != 0 {
+		t.Fatal("silenced incident escalated")
+	}
+
+	// the escalation is retried once the silence ended
+	if err := c.ExpireSilence(silence.ID); err != nil {
+		t.Fatal(err)
+	}
+	c.incidents.check()
+	if got, want := oncall.list(), []string{"alert:jailed"}; !equal(got, want) {
+		t.Fatalf("oncall received %v, want %v", got, want)
+	}
+}
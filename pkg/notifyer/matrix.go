@@ -0,0 +1,98 @@
+package notifyer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// MatrixClient is complient with the Service interface
+//
+// Every notification is posted as a m.room.message event to each of RoomIDs
+// through the client-server API.
+type MatrixClient struct {
+	// Homeserver is the base URL of the homeserver, e.g. https://matrix.org
+	Homeserver  string   `yaml:"homeserver"`
+	AccessToken string   `yaml:"access_token"`
+	RoomIDs     []string `yaml:"room_ids"`
+}
+
+func (c MatrixClient) validate() error {
+	if c.Homeserver == "" || c.AccessToken == "" || len(c.RoomIDs) == 0 {
+		return errors.New("matrix: homeserver, access_token and room_ids are required")
+	}
+	return nil
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func (c MatrixClient) targets() []string {
+	return c.RoomIDs
+}
+
+func (c MatrixClient) target(roomID string) Backend {
+	c.RoomIDs = []string{roomID}
+	return c
+}
+
+// matrixTxnID return the transaction ID of a message, derived from the
+// message so that the homeserver deduplicate a retried message
+func matrixTxnID(e Event, body string) string {
+	sum := sha256.Sum256([]byte(e.Key() + "\x00" + e.Time.Format(time.RFC3339Nano) + "\x00" + body))
+	return "cosmos-notifyer-" + hex.EncodeToString(sum[:16])
+}
+
+func (c MatrixClient) send(e Event, emoji string, content string) error {
+	message := matrixMessage{
+		MsgType:       "m.text",
+		Body:          emoji + " " + content,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: emoji + " <b>" + html.EscapeString(content) + "</b>",
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + c.AccessToken,
+	}
+
+	var errs error
+	for _, roomID := range c.RoomIDs {
+		u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+			strings.TrimSuffix(c.Homeserver, "/"), url.PathEscape(roomID), matrixTxnID(e, message.Body))
+
+		if err := sendJSON(http.MethodPut, u, message, headers); err != nil {
+			errs = errors.Wrap(errs, errors.Annotatef(err, "matrix room %s", roomID))
+		}
+	}
+	return errs
+}
+
+func (c MatrixClient) Alert(msg AlertMsg) error {
+	return c.send(msg.Event, "🚨", msg.Msg)
+}
+
+func (c MatrixClient) Recover(msg RecoverMsg) error {
+	return c.send(msg.Event, "👌", msg.Msg)
+}
+
+func (c MatrixClient) Delegation(msg DelegationMsg) error {
+	return c.send(msg.Event, "🤑", msg.Msg)
+}
+
+func (c MatrixClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(msg.Event, "💸", msg.Msg)
+}
+
+func (c MatrixClient) Info(msg InfoMsg) error {
+	return c.send(msg.Event, "ℹ️", msg.Title+"\n"+msg.Msg)
+}
@@ -1,8 +1,12 @@
 package notifyer
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 )
 
 type Service interface {
@@ -11,104 +15,545 @@ type Service interface {
 	UnDelegation(msg UnDelegationMsg) error
 }
 
+// Backend is implemented by every notification backend (discord, slack...),
+// see Register to add a backend type. A backend return an error when the
+// message is not delivered so that the outbox retry it, and nil for the
+// message types it ignores.
+type Backend interface {
+	Alert(msg AlertMsg) error
+	Recover(msg RecoverMsg) error
+	Delegation(msg DelegationMsg) error
+	UnDelegation(msg UnDelegationMsg) error
+	Info(msg InfoMsg) error
+}
+
 // Client is complient with the Service interface
 type Client struct {
 	Service
 
 	cfg Config
 
-	discordClient *DiscordClient
+	destinations []destination
+
+	db        *bolt.DB
+	outbox    *outbox
+	renderer  *renderer
+	dedup     *dedup
+	silencer  *silencer
+	incidents *incidents
+}
+
+// destination is a named backend, routes refer to destinations by name
+type destination struct {
+	name string
+	// filter select the events accepted by the destination
+	filter Route
+
+	Backend
 }
 
 // Config is Client configuration
 type Config struct {
-	DiscordWebhook string
+	// DatabasePath enable the on-disk database holding the outbox and the
+	// runtime silences. Notifications are then retried until delivered or
+	// older than OutboxMaxAge (default 24h)
+	DatabasePath string
+	OutboxMaxAge time.Duration
+
+	// Dedup hold down alerts and recoveries and suppress flapping conditions
+	Dedup DedupConfig
+
+	// Escalations send unresolved alerts to more destinations
+	Escalations []Escalation
+
+	// Silences are the static silences, more can be added at runtime
+	Silences []Silence
+
+	// Templates override the rendering of messages
+	Templates Templates
+
+	// Explorers are the block explorer URL patterns keyed by chain name
+	Explorers map[string]Explorer
+
+	// Destinations are the named backends
+	Destinations []DestinationConfig
+
+	// Routes select the destinations of each event, every destination
+	// receive every event when no routes are configured. The destinations
+	// named in no route nor escalation keep receiving every event.
+	Routes []Route
 }
 
 // NewClient return a notifyer.Client compatible with Service interface
-func NewClient(cfg Config) *Client {
+func NewClient(cfg Config) (*Client, error) {
 	c := Client{
 		cfg: cfg,
 	}
 
-	if cfg.DiscordWebhook != "" {
-		c.discordClient = &DiscordClient{
-			Webhook: cfg.DiscordWebhook,
+	for _, dest := range cfg.Destinations {
+		if dest.Name == "" {
+			return nil, errors.Errorf("destination of type %s: missing name", dest.Type)
+		}
+		if _, ok := c.destination(dest.Name); ok || dest.Name == TemplatesDefault {
+			return nil, errors.Errorf("destination %s: duplicated name", dest.Name)
+		}
+		b, err := newBackend(dest)
+		if err != nil {
+			return nil, errors.Annotatef(err, "destination %s", dest.Name)
+		}
+		c.destinations = append(c.destinations, destination{
+			name:    dest.Name,
+			filter:  dest.filter(),
+			Backend: b,
+		})
+	}
+
+	for i, route := range cfg.Routes {
+		for _, name := range route.Destinations {
+			if _, ok := c.destination(name); !ok {
+				return nil, errors.Errorf("route #%d: unknown destination: %s", i, name)
+			}
+		}
+	}
+
+	for i, esc := range cfg.Escalations {
+		for _, name := range esc.Destinations {
+			if _, ok := c.destination(name); !ok {
+				return nil, errors.Errorf("escalation #%d: unknown destination: %s", i, name)
+			}
+		}
+	}
+
+	for dest := range cfg.Templates {
+		if _, ok := c.destination(dest); !ok && dest != TemplatesDefault {
+			return nil, errors.Errorf("templates: unknown destination: %s", dest)
+		}
+	}
+	var err error
+	if c.renderer, err = newRenderer(cfg.Templates); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if cfg.Dedup.enabled() {
+		c.dedup = newDedup(cfg.Dedup, func(typ msgType, msg interface{}) {
+			c.notify(msgEvent(msg), typ, msg)
+		})
+	}
+
+	if cfg.DatabasePath != "" {
+		if c.db, err = openDB(cfg.DatabasePath); err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.outbox = newOutbox(c.db, cfg.OutboxMaxAge, c.deliverEntries)
+	}
+
+	c.silencer, err = newSilencer(c.db, cfg.Silences, func(msg InfoMsg) {
+		c.Info(msg)
+	})
+	if err != nil {
+		c.Close()
+		return nil, errors.Trace(err)
+	}
+
+	c.incidents, err = newIncidents(c.db, cfg.Escalations, c.escalate, c.refreshInterval(), c.refresh)
+	if err != nil {
+		c.Close()
+		return nil, errors.Trace(err)
+	}
+	return &c, nil
+}
+
+// Close stop the outbox delivery loop and close the database,
+// pending notifications are kept on disk
+func (c *Client) Close() error {
+	if c.incidents != nil {
+		c.incidents.Close()
+	}
+	if c.silencer != nil {
+		c.silencer.Close()
+	}
+	if c.outbox != nil {
+		c.outbox.Close()
+	}
+	if c.db != nil {
+		return errors.Trace(c.db.Close())
+	}
+	return nil
+}
+
+func (c Client) destination(name string) (destination, bool) {
+	for _, d := range c.destinations {
+		if d.name == name {
+			return d, true
 		}
 	}
-	return &c
+	return destination{}, false
 }
 
 type AlertMsg struct {
+	Event
+
 	Msg string
 }
 
 func (c Client) Alert(msg AlertMsg) error {
-	var errs error
+	msg.Event = c.withDefaults(msg.Event, "")
 
-	if c.discordClient != nil {
-		if err := c.discordClient.Alert(msg); err != nil {
-			errs = errors.Wrap(errs, err)
-		}
-	}
-	if errs != nil {
-		logrus.WithError(errs).Error()
+	if c.dedup != nil && !c.dedup.alert(msg) {
+		return nil
 	}
+	c.notify(msg.Event, msgAlert, msg)
 	return nil
 }
 
 type RecoverMsg struct {
+	Event
+
 	Msg string
 }
 
 func (c Client) Recover(msg RecoverMsg) error {
-	var errs error
+	msg.Event = c.withDefaults(msg.Event, "")
 
-	if c.discordClient != nil {
-		if err := c.discordClient.Recover(msg); err != nil {
-			errs = errors.Wrap(errs, err)
-		}
-	}
-	if errs != nil {
-		logrus.WithError(errs).Error()
+	if c.dedup != nil && !c.dedup.recover(msg) {
+		return nil
 	}
+	c.notify(msg.Event, msgRecover, msg)
 	return nil
 }
 
 type DelegationMsg struct {
+	Event
+
 	Amount float64
 	Token  string
+
+	Msg string
 }
 
 func (c Client) Delegation(msg DelegationMsg) error {
-	var errs error
+	msg.Event = c.withDefaults(msg.Event, KindDelegation)
 
-	if c.discordClient != nil {
-		if err := c.discordClient.Delegation(msg); err != nil {
-			errs = errors.Wrap(errs, err)
-		}
-	}
-	if errs != nil {
-		logrus.WithError(errs).Error()
-	}
+	c.notify(msg.Event, msgDelegation, msg)
 	return nil
 }
 
 type UnDelegationMsg struct {
+	Event
+
 	Amount float64
 	Token  string
+
+	Msg string
 }
 
 func (c Client) UnDelegation(msg UnDelegationMsg) error {
+	msg.Event = c.withDefaults(msg.Event, KindUnDelegation)
+
+	c.notify(msg.Event, msgUnDelegation, msg)
+	return nil
+}
+
+// InfoMsg is an informative message such as a summary, Title is a short
+// description and Msg can span several lines
+type InfoMsg struct {
+	Event
+
+	Title string
+	Msg   string
+}
+
+func (c Client) Info(msg InfoMsg) error {
+	msg.Event = c.withDefaults(msg.Event, "")
+
+	c.notify(msg.Event, msgInfo, msg)
+	return nil
+}
+
+// withDefaults fill the kind, severity, time and explorer URL of e
+func (c Client) withDefaults(e Event, kind EventKind) Event {
+	e = e.withDefaults(kind)
+	if e.ExplorerURL == "" {
+		if explorer, ok := c.cfg.Explorers[e.Chain]; ok {
+			e.ExplorerURL = explorer.URL(e)
+		}
+	}
+	return e
+}
+
+// AddSilence create a runtime silence, persisted when the database is enabled
+func (c Client) AddSilence(silence Silence) (Silence, error) {
+	return c.silencer.add(silence)
+}
+
+// ExpireSilence end a silence now, its summary is sent shortly after
+func (c Client) ExpireSilence(id string) error {
+	return c.silencer.expire(id)
+}
+
+// Silences return the silences not ended yet
+func (c Client) Silences() []Silence {
+	return c.silencer.list()
+}
+
+// Unresolved return true when an alert of the chain condition has been sent
+// and not recovered yet, including before a restart when the database is enabled
+func (c Client) Unresolved(chain string, kind EventKind) bool {
+	return c.incidents.unresolved(Event{Chain: chain, Kind: kind}.Key())
+}
+
+// escalate send msg to the destinations of esc, unless it is silenced
+func (c Client) escalate(msg AlertMsg, esc Escalation) bool {
+	if c.silencer.active(msg.Event) {
+		return false
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"chain":        msg.Chain,
+		"kind":         msg.Kind,
+		"after":        esc.After,
+		"destinations": esc.Destinations,
+	}).Warn("alert escalated")
+
+	var errs error
+	for _, name := range esc.Destinations {
+		d, _ := c.destination(name)
+		if !d.filter.Match(msg.Event) {
+			continue
+		}
+		if err := c.send(d, msgAlert, c.renderer.renderMsg(d.name, msgAlert, msg)); err != nil {
+			errs = errors.Wrap(errs, errors.Annotate(err, d.name))
+		}
+	}
+	if errs != nil {
+		logrus.WithError(errs).Error()
+	}
+	return true
+}
+
+// notify send msg to every destination routed for e, errors are logged
+func (c Client) notify(e Event, typ msgType, msg interface{}) {
 	var errs error
 
-	if c.discordClient != nil {
-		if err := c.discordClient.UnDelegation(msg); err != nil {
-			errs = errors.Wrap(errs, err)
+	escalated, notified := c.incidents.track(typ, msg)
+
+	// the recovery of an alert sent before the silence started is sent, so
+	// that the incidents it opened (pagerduty, alertmanager...) are closed
+	if typ != msgRecover || !notified {
+		if silence, ok := c.silencer.silenced(e); ok {
+			logrus.WithFields(logrus.Fields{
+				"silence":  silence.ID,
+				"type":     typ,
+				"chain":    e.Chain,
+				"kind":     e.Kind,
+				"severity": e.Severity,
+			}).Info("notification silenced")
+			return
+		}
+	}
+	if typ == msgAlert {
+		c.incidents.notified(e.Key())
+	}
+
+	for _, d := range c.recipients(e, escalated) {
+		if err := c.send(d, typ, c.renderer.renderMsg(d.name, typ, msg)); err != nil {
+			errs = errors.Wrap(errs, errors.Annotate(err, d.name))
 		}
 	}
 	if errs != nil {
 		logrus.WithError(errs).Error()
 	}
-	return nil
+}
+
+// recipients return the destinations routed for e and the escalated ones,
+// filtered by their own matchers
+func (c Client) recipients(e Event, escalated []string) []destination {
+	destinations := c.route(e)
+	for _, name := range escalated {
+		if d, ok := c.destination(name); ok && !containsDestination(destinations, name) {
+			destinations = append(destinations, d)
+		}
+	}
+
+	ret := make([]destination, 0, len(destinations))
+	for _, d := range destinations {
+		if d.filter.Match(e) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// refreshInterval return the shortest refresh interval of the destinations,
+// 0 when no destination need its alerts refreshed
+func (c Client) refreshInterval() time.Duration {
+	var interval time.Duration
+	for _, d := range c.destinations {
+		r, ok := d.Backend.(refresher)
+		if !ok {
+			continue
+		}
+		if i := r.refreshInterval(); i > 0 && (interval == 0 || i < interval) {
+			interval = i
+		}
+	}
+	return interval
+}
+
+// refresh send the alert of an open incident again to the destinations
+// whose alerts expire. It is not silenced: the alert was sent and the
+// incident is still open.
+func (c Client) refresh(msg AlertMsg, escalated []string) {
+	var errs error
+	for _, d := range c.recipients(msg.Event, escalated) {
+		if _, ok := d.Backend.(refresher); !ok {
+			continue
+		}
+		if err := c.send(d, msgAlert, c.renderer.renderMsg(d.name, msgAlert, msg)); err != nil {
+			errs = errors.Wrap(errs, errors.Annotate(err, d.name))
+		}
+	}
+	if errs != nil {
+		logrus.WithError(errs).Error()
+	}
+}
+
+// msgEvent return the Event of a message
+func msgEvent(msg interface{}) Event {
+	switch m := msg.(type) {
+	case AlertMsg:
+		return m.Event
+	case RecoverMsg:
+		return m.Event
+	case DelegationMsg:
+		return m.Event
+	case UnDelegationMsg:
+		return m.Event
+	case InfoMsg:
+		return m.Event
+	}
+	return Event{}
+}
+
+// send deliver msg to d, through the outbox when it is enabled
+func (c Client) send(d destination, typ msgType, msg interface{}) error {
+	if c.outbox == nil {
+		return d.send(typ, msg)
+	}
+
+	f, ok := d.Backend.(fanout)
+	if !ok {
+		return c.outbox.push(d.name, "", typ, msg)
+	}
+	var errs error
+	for _, t := range f.targets() {
+		if err := c.outbox.push(d.name, t, typ, msg); err != nil {
+			errs = errors.Wrap(errs, errors.Trace(err))
+		}
+	}
+	return errs
+}
+
+// send call the backend method matching typ
+func (d destination) send(typ msgType, msg interface{}) error {
+	switch m := msg.(type) {
+	case AlertMsg:
+		return d.Alert(m)
+	case RecoverMsg:
+		return d.Recover(m)
+	case DelegationMsg:
+		return d.Delegation(m)
+	case UnDelegationMsg:
+		return d.UnDelegation(m)
+	case InfoMsg:
+		return d.Info(m)
+	}
+	return errors.Errorf("unknown message type: %s", typ)
+}
+
+// batcher is implemented by the backends able to coalesce several messages,
+// e.g. discord posting a burst of delegations as a single message
+type batcher interface {
+	// batchable return true when the messages of typ can be coalesced
+	batchable(typ msgType) bool
+	// sendBatch send msgs and return the number of messages delivered, an
+	// error is the failure of the next one
+	sendBatch(msgs []interface{}) (int, error)
+}
+
+// deliverEntries decode the outbox entries of a target and send the first
+// ones to their destination, coalesced when the backend is a batcher. It
+// return the number of entries delivered or dropped.
+func (c Client) deliverEntries(entries []outboxEntry) (int, error) {
+	e := entries[0]
+	d, ok := c.destination(e.Destination)
+	if !ok {
+		logrus.WithField("destination", e.Destination).Error("outbox: destination is not configured anymore, notification dropped")
+		return 1, nil
+	}
+
+	if e.Target != "" {
+		f, ok := d.Backend.(fanout)
+		if !ok || !contains(f.targets(), e.Target) {
+			logrus.WithFields(logrus.Fields{
+				"destination": e.Destination,
+				"target":      e.Target,
+			}).Error("outbox: target is not configured anymore, notification dropped")
+			return 1, nil
+		}
+		d.Backend = f.target(e.Target)
+	}
+
+	types := []msgType{}
+	msgs := []interface{}{}
+	b, ok := d.Backend.(batcher)
+	for _, e := range entries {
+		if len(msgs) > 0 && (!ok || !b.batchable(e.Type) || !b.batchable(types[0])) {
+			break
+		}
+		msg, err := decodeMsg(e.Type, e.Payload)
+		if err != nil {
+			if len(msgs) > 0 {
+				break
+			}
+			logrus.WithError(err).Error("outbox: invalid entry, notification dropped")
+			return 1, nil
+		}
+		types = append(types, e.Type)
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) == 1 {
+		if err := d.send(types[0], msgs[0]); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	return b.sendBatch(msgs)
+}
+
+func decodeMsg(typ msgType, payload []byte) (interface{}, error) {
+	switch typ {
+	case msgAlert:
+		m := AlertMsg{}
+		err := json.Unmarshal(payload, &m)
+		return m, errors.Trace(err)
+	case msgRecover:
+		m := RecoverMsg{}
+		err := json.Unmarshal(payload, &m)
+		return m, errors.Trace(err)
+	case msgDelegation:
+		m := DelegationMsg{}
+		err := json.Unmarshal(payload, &m)
+		return m, errors.Trace(err)
+	case msgUnDelegation:
+		m := UnDelegationMsg{}
+		err := json.Unmarshal(payload, &m)
+		return m, errors.Trace(err)
+	case msgInfo:
+		m := InfoMsg{}
+		err := json.Unmarshal(payload, &m)
+		return m, errors.Trace(err)
+	}
+	return nil, errors.Errorf("unknown message type: %s", typ)
 }
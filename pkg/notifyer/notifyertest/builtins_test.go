@@ -0,0 +1,14 @@
+package notifyertest
+
+import (
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	for typ, newBackend := range Builtins() {
+		newBackend := newBackend
+		t.Run(typ, func(t *testing.T) {
+			Run(t, newBackend)
+		})
+	}
+}
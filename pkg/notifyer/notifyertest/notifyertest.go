@@ -0,0 +1,255 @@
+// Package notifyertest provide a conformance suite for notifyer.Backend
+// implementations, run against a local HTTP capture server, e.g.
+//
+//	func TestConformance(t *testing.T) {
+//		notifyertest.Run(t, func(url string) notifyer.Backend {
+//			return &SMSClient{URL: url}
+//		})
+//	}
+package notifyertest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nysa-network/pkg/notifyer"
+)
+
+// Request is a request received by a CaptureServer
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// CaptureServer is a local HTTP server recording the requests it receives,
+// it respond with Status (default 200) and an empty JSON object.
+type CaptureServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []Request
+}
+
+// NewCaptureServer start a CaptureServer, it must be closed
+func NewCaptureServer() *CaptureServer {
+	s := &CaptureServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *CaptureServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	status := s.status
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte("{}"))
+}
+
+// SetStatus change the status of the next responses
+func (s *CaptureServer) SetStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// Requests return the requests received since the latest Reset
+func (s *CaptureServer) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forget the received requests and respond 200 again
+func (s *CaptureServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.status = http.StatusOK
+}
+
+// alertText is the message of the conformance alert, without characters
+// escaped by any backend
+const alertText = "conformance alert on juno"
+
+func event(kind notifyer.EventKind) notifyer.Event {
+	return notifyer.Event{
+		Kind:        kind,
+		Severity:    notifyer.DefaultSeverity(kind),
+		Chain:       "juno",
+		Moniker:     "validator",
+		Validator:   "junovaloper1conformance",
+		Height:      42,
+		Time:        time.Now().UTC(),
+		ExplorerURL: "https://explorer.example.com/juno/blocks/42",
+		Fields: map[string]string{
+			"missed_blocks": "10",
+		},
+	}
+}
+
+// Run check the backend built by newBackend, sending to the capture server
+// URL, against the conformance suite:
+//   - alerts are delivered and carry the message
+//   - recoveries are delivered
+//   - delegations, undelegations and infos either are delivered or ignored
+//   - a server error is returned so that the outbox retry the message
+func Run(t *testing.T, newBackend func(url string) notifyer.Backend) {
+	srv := NewCaptureServer()
+	defer srv.Close()
+
+	b := newBackend(srv.URL)
+
+	t.Run("alert", func(t *testing.T) {
+		srv.Reset()
+		err := b.Alert(notifyer.AlertMsg{
+			Event: event(notifyer.KindMissedBlocks),
+			Msg:   alertText,
+		})
+		if err != nil {
+			t.Fatalf("alert: %s", err)
+		}
+
+		requests := srv.Requests()
+		if len(requests) == 0 {
+			t.Fatal("alert: no request received")
+		}
+		for _, r := range requests {
+			if !bytes.Contains(r.Body, []byte(alertText)) {
+				t.Errorf("alert: %s %s: message not found in body: %s", r.Method, r.Path, r.Body)
+			}
+		}
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		srv.Reset()
+		err := b.Recover(notifyer.RecoverMsg{
+			Event: event(notifyer.KindMissedBlocks),
+			Msg:   "conformance recovery on juno",
+		})
+		if err != nil {
+			t.Fatalf("recover: %s", err)
+		}
+		if len(srv.Requests()) == 0 {
+			t.Fatal("recover: no request received")
+		}
+	})
+
+	t.Run("delegation", func(t *testing.T) {
+		srv.Reset()
+		err := b.Delegation(notifyer.DelegationMsg{
+			Event:  event(notifyer.KindDelegation),
+			Amount: 1234.5,
+			Token:  "JUNO",
+			Msg:    "conformance delegation on juno",
+		})
+		if err != nil {
+			t.Fatalf("delegation: %s", err)
+		}
+	})
+
+	t.Run("undelegation", func(t *testing.T) {
+		srv.Reset()
+		err := b.UnDelegation(notifyer.UnDelegationMsg{
+			Event:  event(notifyer.KindUnDelegation),
+			Amount: 1234.5,
+			Token:  "JUNO",
+			Msg:    "conformance undelegation on juno",
+		})
+		if err != nil {
+			t.Fatalf("undelegation: %s", err)
+		}
+	})
+
+	t.Run("info", func(t *testing.T) {
+		srv.Reset()
+		err := b.Info(notifyer.InfoMsg{
+			Event: event(notifyer.KindDigest),
+			Title: "conformance info",
+			Msg:   "first line\nsecond line",
+		})
+		if err != nil {
+			t.Fatalf("info: %s", err)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		srv.Reset()
+		srv.SetStatus(http.StatusInternalServerError)
+		err := b.Alert(notifyer.AlertMsg{
+			Event: event(notifyer.KindJailed),
+			Msg:   alertText,
+		})
+		if err == nil {
+			t.Fatal("alert: no error returned on a server error")
+		}
+	})
+}
+
+// Builtins return the constructors of the built-in HTTP backends sending to
+// url, keyed by type. The email and exec backends are not HTTP based.
+//
+//	for typ, newBackend := range notifyertest.Builtins() {
+//		t.Run(typ, func(t *testing.T) {
+//			notifyertest.Run(t, newBackend)
+//		})
+//	}
+func Builtins() map[string]func(url string) notifyer.Backend {
+	return map[string]func(url string) notifyer.Backend{
+		"discord": func(url string) notifyer.Backend {
+			return &notifyer.DiscordClient{Webhook: url}
+		},
+		"slack": func(url string) notifyer.Backend {
+			return &notifyer.SlackClient{Webhook: url}
+		},
+		"telegram": func(url string) notifyer.Backend {
+			return &notifyer.TelegramClient{Token: "token", ChatIDs: []string{"1", "2"}, APIURL: url}
+		},
+		"pagerduty": func(url string) notifyer.Backend {
+			return &notifyer.PagerDutyClient{RoutingKey: "key", APIURL: url}
+		},
+		"opsgenie": func(url string) notifyer.Backend {
+			return &notifyer.OpsgenieClient{APIKey: "key", APIURL: url}
+		},
+		"webhook": func(url string) notifyer.Backend {
+			return &notifyer.WebhookClient{URLs: []string{url}, Secret: "secret"}
+		},
+		"matrix": func(url string) notifyer.Backend {
+			return &notifyer.MatrixClient{Homeserver: url, AccessToken: "token", RoomIDs: []string{"!room"}}
+		},
+		"ntfy": func(url string) notifyer.Backend {
+			return &notifyer.NtfyClient{URL: url}
+		},
+		"gotify": func(url string) notifyer.Backend {
+			return &notifyer.GotifyClient{URL: url, Token: "token"}
+		},
+		"teams": func(url string) notifyer.Backend {
+			return &notifyer.TeamsClient{Webhook: url}
+		},
+		"alertmanager": func(url string) notifyer.Backend {
+			return &notifyer.AlertmanagerClient{URL: url}
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package notifyer
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/juju/errors"
+)
+
+const opsgenieAPI = "https://api.opsgenie.com"
+
+// OpsgenieClient is complient with the Service interface
+//
+// Alerts are created with a priority derived from their kind and the
+// condition key as alias, recoveries close the alert by alias.
+// Delegations are not forwarded.
+type OpsgenieClient struct {
+	APIKey string `yaml:"api_key"`
+	// APIURL default to https://api.opsgenie.com, use https://api.eu.opsgenie.com for EU accounts
+	APIURL string `yaml:"api_url"`
+}
+
+func (c OpsgenieClient) validate() error {
+	if c.APIKey == "" {
+		return errors.New("opsgenie: api_key is required")
+	}
+	return nil
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Source      string            `json:"source"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func opsgeniePriority(kind EventKind) string {
+	switch kind {
+	case KindJailed, KindTombstoned:
+		return "P1"
+	case KindMissedBlocks, KindInactive:
+		return "P2"
+	case KindRPCDown:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
+
+func (c OpsgenieClient) apiURL() string {
+	if c.APIURL == "" {
+		return opsgenieAPI
+	}
+	return c.APIURL
+}
+
+func (c OpsgenieClient) headers() map[string]string {
+	return map[string]string{
+		"Authorization": "GenieKey " + c.APIKey,
+	}
+}
+
+func (c OpsgenieClient) Alert(msg AlertMsg) error {
+	message := msg.Msg
+	// opsgenie reject messages longer than 130 characters
+	if r := []rune(message); len(r) > 130 {
+		message = string(r[:127]) + "..."
+	}
+
+	details := map[string]string{}
+	for _, d := range msg.details() {
+		details[d.Label] = d.Value
+	}
+
+	alert := opsgenieAlert{
+		Message:     message,
+		Alias:       msg.Key(),
+		Description: msg.Msg,
+		Priority:    opsgeniePriority(msg.Kind),
+		Tags:        []string{msg.Chain, string(msg.Kind), string(msg.Severity)},
+		Details:     details,
+		Source:      "cosmos-notifyer",
+	}
+
+	if err := postJSON(c.apiURL()+"/v2/alerts", alert, c.headers()); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c OpsgenieClient) Recover(msg RecoverMsg) error {
+	u := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias",
+		c.apiURL(), url.PathEscape(msg.Key()))
+
+	body := opsgenieClose{
+		Source: "cosmos-notifyer",
+		Note:   msg.Msg,
+	}
+
+	if err := postJSON(u, body, c.headers()); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c OpsgenieClient) Delegation(msg DelegationMsg) error {
+	return nil
+}
+
+func (c OpsgenieClient) UnDelegation(msg UnDelegationMsg) error {
+	return nil
+}
+
+func (c OpsgenieClient) Info(msg InfoMsg) error {
+	return nil
+}
@@ -0,0 +1,416 @@
+package notifyer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	outboxMinBackoff     = 5 * time.Second
+	outboxMaxBackoff     = 10 * time.Minute
+	outboxDefaultMaxAge  = 24 * time.Hour
+	outboxReportInterval = time.Minute
+	// outboxMaxBatch is the maximum number of entries delivered at once
+	outboxMaxBatch = 50
+)
+
+var outboxBucket = []byte("outbox")
+
+// msgType is the Service method a message is sent through
+type msgType string
+
+const (
+	msgAlert        msgType = "alert"
+	msgRecover      msgType = "recover"
+	msgDelegation   msgType = "delegation"
+	msgUnDelegation msgType = "undelegation"
+	msgInfo         msgType = "info"
+)
+
+// fanout is implemented by the backends sending every message to several
+// targets (chats, URLs, rooms), the outbox then store an entry per target so
+// that a retry is only sent to the targets which failed
+type fanout interface {
+	targets() []string
+	// target return the backend sending to the target only
+	target(t string) Backend
+}
+
+// outboxEntry is a message waiting to be delivered to a destination, or to
+// one Target of a fanout destination
+type outboxEntry struct {
+	ID          uint64          `json:"id"`
+	Destination string          `json:"destination"`
+	Target      string          `json:"target,omitempty"`
+	Type        msgType         `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// outbox persist messages on disk until they are delivered, failed
+// deliveries are retried with exponential backoff and jitter, pending
+// messages are replayed after a restart. Each destination is delivered by
+// its own worker so that a slow or failing destination does not delay the
+// other ones.
+type outbox struct {
+	db     *bolt.DB
+	maxAge time.Duration
+
+	// deliver send the first entries, of a single target, and return the
+	// number of entries delivered. An error is the failure of the next one.
+	deliver func(entries []outboxEntry) (int, error)
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	// workers hold the wake up channel of the worker of each destination
+	mu      sync.Mutex
+	workers map[string]chan struct{}
+
+	delivered uint64
+	failures  uint64
+	dropped   uint64
+}
+
+func newOutbox(db *bolt.DB, maxAge time.Duration, deliver func(entries []outboxEntry) (int, error)) *outbox {
+	if maxAge == 0 {
+		maxAge = outboxDefaultMaxAge
+	}
+
+	o := &outbox{
+		db:      db,
+		maxAge:  maxAge,
+		deliver: deliver,
+		stop:    make(chan struct{}),
+		workers: map[string]chan struct{}{},
+	}
+
+	if depth := o.depth(); depth > 0 {
+		logrus.WithField("depth", depth).Info("outbox: replaying pending notifications")
+	}
+	for _, destination := range o.destinations() {
+		o.wake(destination)
+	}
+
+	o.wg.Add(1)
+	go o.report()
+	return o
+}
+
+// Close stop the delivery workers, pending entries stay in the database
+func (o *outbox) Close() {
+	o.mu.Lock()
+	close(o.stop)
+	o.mu.Unlock()
+	o.wg.Wait()
+}
+
+// push store msg for the target of destination and wake up its worker
+func (o *outbox) push(destination string, target string, typ msgType, msg interface{}) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = o.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(outboxBucket)
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry := outboxEntry{
+			ID:          id,
+			Destination: destination,
+			Target:      target,
+			Type:        typ,
+			Payload:     payload,
+			CreatedAt:   time.Now(),
+			NextAttempt: time.Now(),
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put(outboxKey(id), data)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	o.wake(destination)
+	return nil
+}
+
+// wake up the worker of destination, starting it when needed
+func (o *outbox) wake(destination string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	select {
+	case <-o.stop:
+		return
+	default:
+	}
+
+	wake, ok := o.workers[destination]
+	if !ok {
+		wake = make(chan struct{}, 1)
+		o.workers[destination] = wake
+		o.wg.Add(1)
+		go o.worker(destination, wake)
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func (o *outbox) depth() int {
+	depth := 0
+	_ = o.db.View(func(tx *bolt.Tx) error {
+		depth = tx.Bucket(outboxBucket).Stats().KeyN
+		return nil
+	})
+	return depth
+}
+
+// entries return the pending entries, in order, of destination or of every
+// destination when empty
+func (o *outbox) entries(destination string) []outboxEntry {
+	var entries []outboxEntry
+
+	_ = o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(k, v []byte) error {
+			e := outboxEntry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				logrus.WithError(err).Error("outbox: invalid entry")
+				return nil
+			}
+			if destination == "" || e.Destination == destination {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+	return entries
+}
+
+// destinations return the destinations with pending entries
+func (o *outbox) destinations() []string {
+	var ret []string
+	for _, e := range o.entries("") {
+		if !contains(ret, e.Destination) {
+			ret = append(ret, e.Destination)
+		}
+	}
+	return ret
+}
+
+// report log the pending entries periodically
+func (o *outbox) report() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(outboxReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			if depth := o.depth(); depth > 0 {
+				logrus.WithFields(logrus.Fields{
+					"depth":     depth,
+					"delivered": atomic.LoadUint64(&o.delivered),
+					"failures":  atomic.LoadUint64(&o.failures),
+					"dropped":   atomic.LoadUint64(&o.dropped),
+				}).Warn("outbox: notifications pending")
+			}
+		}
+	}
+}
+
+// worker deliver the entries of destination until the outbox is closed
+func (o *outbox) worker(destination string, wake chan struct{}) {
+	defer o.wg.Done()
+
+	for {
+		next := o.flush(destination)
+
+		wait := time.Until(next)
+		if next.IsZero() || wait > outboxMaxBackoff {
+			wait = outboxMaxBackoff
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-o.stop:
+			timer.Stop()
+			return
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// flush try to deliver every due entry of destination and return when the
+// next entry is due. Entries of a target are delivered in order, a failing
+// entry block the following ones of the same target. The due entries
+// following an entry are passed along so that deliver can coalesce them.
+func (o *outbox) flush(destination string) time.Time {
+	var next time.Time
+	blocked := map[string]bool{}
+	delivered := map[uint64]bool{}
+
+	entries := o.entries(destination)
+	for i, e := range entries {
+		select {
+		case <-o.stop:
+			return next
+		default:
+		}
+
+		if blocked[e.Target] || delivered[e.ID] {
+			continue
+		}
+
+		if time.Since(e.CreatedAt) > o.maxAge {
+			atomic.AddUint64(&o.dropped, 1)
+			entryLogger(e).WithField("last_error", e.LastError).Error("outbox: notification expired, dropped")
+			o.delete(e.ID)
+			continue
+		}
+
+		if time.Now().Before(e.NextAttempt) {
+			blocked[e.Target] = true
+			if next.IsZero() || e.NextAttempt.Before(next) {
+				next = e.NextAttempt
+			}
+			continue
+		}
+
+		batch := []outboxEntry{e}
+		for _, f := range entries[i+1:] {
+			if len(batch) == outboxMaxBatch {
+				break
+			}
+			if f.Target != e.Target {
+				continue
+			}
+			if time.Since(f.CreatedAt) > o.maxAge || time.Now().Before(f.NextAttempt) {
+				break
+			}
+			batch = append(batch, f)
+		}
+
+		n, err := o.deliver(batch)
+		if n > len(batch) || (err != nil && n == len(batch)) {
+			n = len(batch)
+			if err != nil {
+				n--
+			}
+		}
+		if n == 0 && err == nil {
+			err = errors.New("nothing delivered")
+		}
+		for _, d := range batch[:n] {
+			atomic.AddUint64(&o.delivered, 1)
+			if d.Attempts > 0 {
+				entryLogger(d).Info("outbox: delivered after retry")
+			}
+			delivered[d.ID] = true
+			o.delete(d.ID)
+		}
+		if err == nil {
+			continue
+		}
+
+		failed := batch[n]
+		atomic.AddUint64(&o.failures, 1)
+		blocked[e.Target] = true
+
+		failed.Attempts += 1
+		failed.LastError = err.Error()
+		failed.NextAttempt = time.Now().Add(outboxBackoff(failed.Attempts))
+		if next.IsZero() || failed.NextAttempt.Before(next) {
+			next = failed.NextAttempt
+		}
+
+		entryLogger(failed).WithError(err).WithFields(logrus.Fields{
+			"next_attempt": failed.NextAttempt.Format(time.RFC3339),
+			"depth":        o.depth(),
+			"failures":     atomic.LoadUint64(&o.failures),
+		}).Warn("outbox: delivery failed")
+
+		o.update(failed)
+	}
+	return next
+}
+
+// entryLogger return a logger with the fields of e
+func entryLogger(e outboxEntry) *logrus.Entry {
+	l := logrus.WithFields(logrus.Fields{
+		"destination": e.Destination,
+		"type":        e.Type,
+		"attempts":    e.Attempts,
+	})
+	if e.Target != "" {
+		l = l.WithField("target", e.Target)
+	}
+	return l
+}
+
+func (o *outbox) update(e outboxEntry) {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(outboxBucket).Put(outboxKey(e.ID), data)
+	})
+	if err != nil {
+		logrus.WithError(err).Error("outbox: failed to update entry")
+	}
+}
+
+func (o *outbox) delete(id uint64) {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete(outboxKey(id))
+	})
+	if err != nil {
+		logrus.WithError(err).Error("outbox: failed to delete entry")
+	}
+}
+
+// outboxBackoff return a random delay in [d/2, d] where d grow
+// exponentially with attempts
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxMinBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func outboxKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,277 @@
+package notifyer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// eventually fail the test when cond is still false after 5s
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// one return an outbox deliver function delivering the entries one by one
+func one(deliver func(e outboxEntry) error) func(entries []outboxEntry) (int, error) {
+	return func(entries []outboxEntry) (int, error) {
+		if err := deliver(entries[0]); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+}
+
+// idleOutbox return an outbox without workers for destinations, their
+// entries are only delivered by flush
+func idleOutbox(db *bolt.DB, maxAge time.Duration, deliver func(entries []outboxEntry) (int, error), destinations ...string) *outbox {
+	o := &outbox{
+		db:      db,
+		maxAge:  maxAge,
+		deliver: deliver,
+		stop:    make(chan struct{}),
+		workers: map[string]chan struct{}{},
+	}
+	for _, d := range destinations {
+		o.workers[d] = make(chan struct{}, 1)
+	}
+	return o
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		max      time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{5, 80 * time.Second},
+		{8, outboxMaxBackoff},
+		{100, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d := outboxBackoff(tt.attempts)
+			if d < tt.max/2 || d > tt.max {
+				t.Fatalf("attempts %d: backoff %s not in [%s, %s]", tt.attempts, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestOutboxReplay(t *testing.T) {
+	db := testDB(t)
+
+	// entries pushed after Close are stored but not delivered
+	o := newOutbox(db, 0, one(func(e outboxEntry) error {
+		t.Error("closed outbox delivered an entry")
+		return nil
+	}))
+	o.Close()
+	if err := o.push("discord", "", msgAlert, AlertMsg{Msg: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.push("discord", "", msgAlert, AlertMsg{Msg: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var delivered []string
+	o = newOutbox(db, 0, one(func(e outboxEntry) error {
+		msg, err := decodeMsg(e.Type, e.Payload)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		delivered = append(delivered, msg.(AlertMsg).Msg)
+		mu.Unlock()
+		return nil
+	}))
+	defer o.Close()
+
+	eventually(t, "replay", func() bool { return o.depth() == 0 })
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) != 2 || delivered[0] != "first" || delivered[1] != "second" {
+		t.Fatalf("delivered %v, want [first second]", delivered)
+	}
+}
+
+func TestOutboxExpired(t *testing.T) {
+	db := testDB(t)
+
+	o := idleOutbox(db, time.Nanosecond, one(func(e outboxEntry) error {
+		t.Error("expired entry delivered")
+		return nil
+	}), "discord")
+	if err := o.push("discord", "", msgAlert, AlertMsg{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	o.flush("discord")
+	if o.depth() != 0 {
+		t.Fatal("expired entry not dropped")
+	}
+	if o.dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", o.dropped)
+	}
+}
+
+func TestOutboxFailedEntryBlockTarget(t *testing.T) {
+	db := testDB(t)
+
+	var delivered []string
+	o := idleOutbox(db, outboxDefaultMaxAge, one(func(e outboxEntry) error {
+		if e.Target == "down" {
+			return errors.New("down")
+		}
+		delivered = append(delivered, e.Target)
+		return nil
+	}), "webhook")
+	for _, target := range []string{"down", "up", "down", "up"} {
+		if err := o.push("webhook", target, msgAlert, AlertMsg{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	next := o.flush("webhook")
+	if len(delivered) != 2 {
+		t.Fatalf("delivered %v, want the 2 entries of up", delivered)
+	}
+	if o.failures != 1 {
+		t.Fatalf("failures = %d, want 1, the second entry of down must wait", o.failures)
+	}
+	if wait := time.Until(next); wait <= 0 || wait > outboxMinBackoff {
+		t.Fatalf("next attempt in %s", wait)
+	}
+
+	entries := o.entries("webhook")
+	if len(entries) != 2 || entries[0].Attempts != 1 || entries[0].LastError == "" || entries[1].Attempts != 0 {
+		t.Fatalf("unexpected pending entries: %+v", entries)
+	}
+}
+
+func TestOutboxBatch(t *testing.T) {
+	db := testDB(t)
+
+	batches := []int{}
+	o := idleOutbox(db, outboxDefaultMaxAge, func(entries []outboxEntry) (int, error) {
+		batches = append(batches, len(entries))
+		return 2, errors.New("third entry failed")
+	}, "discord")
+	for i := 0; i < 4; i++ {
+		if err := o.push("discord", "", msgDelegation, DelegationMsg{Amount: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o.flush("discord")
+	if len(batches) != 1 || batches[0] != 4 {
+		t.Fatalf("batches %v, want a single batch of 4 entries", batches)
+	}
+	entries := o.entries("discord")
+	if len(entries) != 2 || entries[0].Attempts != 1 || entries[1].Attempts != 0 {
+		t.Fatalf("unexpected pending entries: %+v", entries)
+	}
+	if o.delivered != 2 || o.failures != 1 {
+		t.Fatalf("delivered = %d, failures = %d", o.delivered, o.failures)
+	}
+}
+
+func TestOutboxSlowDestination(t *testing.T) {
+	db := testDB(t)
+
+	release := make(chan struct{})
+	delivered := make(chan string, 2)
+	o := newOutbox(db, 0, one(func(e outboxEntry) error {
+		if e.Destination == "slow" {
+			<-release
+		}
+		delivered <- e.Destination
+		return nil
+	}))
+	defer o.Close()
+
+	if err := o.push("slow", "", msgAlert, AlertMsg{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.push("pagerduty", "", msgAlert, AlertMsg{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-delivered:
+		if d != "pagerduty" {
+			t.Fatalf("%s delivered first", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pagerduty delayed by the slow destination")
+	}
+	close(release)
+	<-delivered
+}
+
+func TestClientFanoutRetryFailedTargetOnly(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{
+		DatabasePath: filepath.Join(t.TempDir(), "test.db"),
+		Destinations: []DestinationConfig{{
+			Name:    "hooks",
+			Backend: &WebhookClient{URLs: []string{srv.URL + "/up", srv.URL + "/down"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Alert(AlertMsg{Event: Event{Kind: KindJailed, Chain: "juno"}})
+
+	eventually(t, "the failed delivery", func() bool {
+		entries := c.outbox.entries("hooks")
+		return len(entries) == 1 && entries[0].Attempts == 1
+	})
+	if target := c.outbox.entries("hooks")[0].Target; target != srv.URL+"/down" {
+		t.Fatalf("pending target %s, want the failed one", target)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/up"] != 1 {
+		t.Fatalf("/up received %d requests, want 1", requests["/up"])
+	}
+}
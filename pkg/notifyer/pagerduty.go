@@ -0,0 +1,114 @@
+package notifyer
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const pagerDutyEventsAPI = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyClient is complient with the Service interface
+//
+// Alerts trigger an incident through the Events API v2 and recoveries
+// resolve the incident sharing the same dedup key (chain/kind).
+// Delegations are not paged.
+type PagerDutyClient struct {
+	RoutingKey string `yaml:"routing_key"`
+	// APIURL default to https://events.pagerduty.com/v2/enqueue
+	APIURL string `yaml:"api_url"`
+}
+
+func (c PagerDutyClient) validate() error {
+	if c.RoutingKey == "" {
+		return errors.New("pagerduty: routing_key is required")
+	}
+	return nil
+}
+
+func (c PagerDutyClient) apiURL() string {
+	if c.APIURL == "" {
+		return pagerDutyEventsAPI
+	}
+	return c.APIURL
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+func pagerDutySeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func (c PagerDutyClient) Alert(msg AlertMsg) error {
+	details := map[string]string{}
+	for _, d := range msg.details() {
+		details[d.Label] = d.Value
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  c.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    msg.Key(),
+		Payload: &pagerDutyPayload{
+			Summary:       msg.Msg,
+			Source:        "cosmos-notifyer",
+			Severity:      pagerDutySeverity(msg.Severity),
+			Timestamp:     msg.Time.Format(time.RFC3339),
+			Group:         msg.Chain,
+			Class:         string(msg.Kind),
+			CustomDetails: details,
+		},
+	}
+
+	if err := postJSON(c.apiURL(), event, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c PagerDutyClient) Recover(msg RecoverMsg) error {
+	event := pagerDutyEvent{
+		RoutingKey:  c.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    msg.Key(),
+	}
+
+	if err := postJSON(c.apiURL(), event, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c PagerDutyClient) Delegation(msg DelegationMsg) error {
+	return nil
+}
+
+func (c PagerDutyClient) UnDelegation(msg UnDelegationMsg) error {
+	return nil
+}
+
+func (c PagerDutyClient) Info(msg InfoMsg) error {
+	return nil
+}
@@ -0,0 +1,163 @@
+package notifyer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// pushLevel is how loud a push notification should be on the phone
+type pushLevel int
+
+const (
+	pushSilent pushLevel = iota
+	pushDefault
+	pushUrgent
+)
+
+// ntfy priorities go from 1 (min) to 5 (max)
+func (l pushLevel) ntfy() int {
+	switch l {
+	case pushUrgent:
+		return 5
+	case pushDefault:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// gotify priorities go from 0 (no notification) to 10, 8+ is displayed as urgent
+func (l pushLevel) gotify() int {
+	switch l {
+	case pushUrgent:
+		return 8
+	case pushDefault:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// alertPushLevel return how loud an alert of severity should be
+func alertPushLevel(severity Severity) pushLevel {
+	if severity == SeverityCritical {
+		return pushUrgent
+	}
+	return pushDefault
+}
+
+// NtfyClient is complient with the Service interface
+//
+// Critical alerts are published with the max priority so they buzz phones,
+// delegations are published with the min priority and stay silent.
+type NtfyClient struct {
+	// URL is the topic URL, e.g. https://ntfy.sh/my-validator
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+func (c NtfyClient) validate() error {
+	if c.URL == "" {
+		return errors.New("ntfy: url is required")
+	}
+	return nil
+}
+
+func (c NtfyClient) send(level pushLevel, title string, content string, tags ...string) error {
+	headers := map[string]string{
+		"Title":    title,
+		"Priority": strconv.Itoa(level.ntfy()),
+		"Tags":     strings.Join(tags, ","),
+	}
+	if c.Token != "" {
+		headers["Authorization"] = "Bearer " + c.Token
+	}
+
+	if err := post(c.URL, "text/plain; charset=utf-8", []byte(content), headers); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c NtfyClient) Alert(msg AlertMsg) error {
+	return c.send(alertPushLevel(msg.Severity), "Alert", msg.Msg,
+		"rotating_light", msg.Chain, string(msg.Kind), string(msg.Severity))
+}
+
+func (c NtfyClient) Recover(msg RecoverMsg) error {
+	return c.send(pushDefault, "Recovered", msg.Msg, "ok_hand", msg.Chain, string(msg.Kind))
+}
+
+func (c NtfyClient) Delegation(msg DelegationMsg) error {
+	return c.send(pushSilent, "Delegation", msg.Msg, "money_mouth_face", msg.Chain)
+}
+
+func (c NtfyClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(pushSilent, "Undelegation", msg.Msg, "money_with_wings", msg.Chain)
+}
+
+func (c NtfyClient) Info(msg InfoMsg) error {
+	return c.send(pushSilent, msg.Title, msg.Msg, "information_source", msg.Chain)
+}
+
+// GotifyClient is complient with the Service interface
+//
+// Critical alerts are sent with an urgent priority, delegations with priority 0
+// which does not trigger a phone notification.
+type GotifyClient struct {
+	// URL is the gotify server base URL, e.g. https://gotify.example.com
+	URL string `yaml:"url"`
+	// Token is an application token
+	Token string `yaml:"token"`
+}
+
+func (c GotifyClient) validate() error {
+	if c.URL == "" || c.Token == "" {
+		return errors.New("gotify: url and token are required")
+	}
+	return nil
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+func (c GotifyClient) send(level pushLevel, title string, content string) error {
+	u := strings.TrimSuffix(c.URL, "/") + "/message"
+
+	message := gotifyMessage{
+		Title:    title,
+		Message:  content,
+		Priority: level.gotify(),
+	}
+
+	// the token is sent as a header so that it never show up in errors
+	if err := postJSON(u, message, map[string]string{"X-Gotify-Key": c.Token}); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c GotifyClient) Alert(msg AlertMsg) error {
+	return c.send(alertPushLevel(msg.Severity), "🚨 Alert", msg.Msg)
+}
+
+func (c GotifyClient) Recover(msg RecoverMsg) error {
+	return c.send(pushDefault, "👌 Recovered", msg.Msg)
+}
+
+func (c GotifyClient) Delegation(msg DelegationMsg) error {
+	return c.send(pushSilent, "🤑 Delegation", msg.Msg)
+}
+
+func (c GotifyClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(pushSilent, "💸 Undelegation", msg.Msg)
+}
+
+func (c GotifyClient) Info(msg InfoMsg) error {
+	return c.send(pushSilent, "ℹ️ "+msg.Title, msg.Msg)
+}
@@ -0,0 +1,119 @@
+package notifyer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// Decoder decode the settings of a destination into v, a pointer to a
+// struct whose fields have yaml tags
+type Decoder func(v interface{}) error
+
+// BackendFactory return a backend configured by the settings of a destination
+type BackendFactory func(decode Decoder) (Backend, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]BackendFactory{}
+)
+
+func init() {
+	Register("discord", decodedBackend[DiscordClient]())
+	Register("slack", decodedBackend[SlackClient]())
+	Register("telegram", decodedBackend[TelegramClient]())
+	Register("pagerduty", decodedBackend[PagerDutyClient]())
+	Register("opsgenie", decodedBackend[OpsgenieClient]())
+	Register("webhook", decodedBackend[WebhookClient]())
+	Register("email", decodedBackend[EmailClient]())
+	Register("matrix", decodedBackend[MatrixClient]())
+	Register("ntfy", decodedBackend[NtfyClient]())
+	Register("gotify", decodedBackend[GotifyClient]())
+	Register("teams", decodedBackend[TeamsClient]())
+	Register("exec", decodedBackend[ExecClient]())
+	Register("alertmanager", decodedBackend[AlertmanagerClient]())
+}
+
+// Register make a backend type available to DestinationConfig.Type, it
+// panics when the type is already registered. Register is meant to be
+// called from an init function, e.g.
+//
+//	func init() {
+//		notifyer.Register("sms", func(decode notifyer.Decoder) (notifyer.Backend, error) {
+//			b := &SMSClient{}
+//			return b, decode(b)
+//		})
+//	}
+func Register(typ string, factory BackendFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("notifyer: Register factory is nil for " + typ)
+	}
+	if _, ok := registry[typ]; ok {
+		panic("notifyer: Register called twice for " + typ)
+	}
+	registry[typ] = factory
+}
+
+// Backends return the registered backend types, sorted
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	ret := make([]string, 0, len(registry))
+	for typ := range registry {
+		ret = append(ret, typ)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// validator is implemented by the backends checking their settings
+type validator interface {
+	// validate return an error when a required setting is missing
+	validate() error
+}
+
+// decodedBackend return a factory decoding the settings into a new T, then
+// validating them
+func decodedBackend[T any, P interface {
+	*T
+	Backend
+}]() BackendFactory {
+	return func(decode Decoder) (Backend, error) {
+		b := P(new(T))
+		if err := decode(b); err != nil {
+			return nil, err
+		}
+		if v, ok := Backend(b).(validator); ok {
+			if err := v.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+}
+
+// newBackend return the backend of the destination type, configured from
+// the destination settings
+func newBackend(d DestinationConfig) (Backend, error) {
+	if d.Backend != nil {
+		return d.Backend, nil
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[d.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("unknown type: %q", d.Type)
+	}
+
+	b, err := factory(d.Decode)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return b, nil
+}
@@ -0,0 +1,100 @@
+package notifyer
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testBackend is a Backend registered by the tests
+type testBackend struct {
+	recorder
+
+	Number string `yaml:"number"`
+}
+
+func init() {
+	Register("test-sms", decodedBackend[testBackend]())
+}
+
+func TestRegistry(t *testing.T) {
+	d := DestinationConfig{}
+	err := yaml.Unmarshal([]byte(`
+name: oncall
+type: test-sms
+kinds: [jailed]
+number: "+33600000000"
+`), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "oncall" || d.Type != "test-sms" || len(d.Kinds) != 1 {
+		t.Fatalf("unexpected destination: %+v", d)
+	}
+
+	b, err := newBackend(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sms, ok := b.(*testBackend)
+	if !ok {
+		t.Fatalf("backend is a %T", b)
+	}
+	if sms.Number != "+33600000000" {
+		t.Fatalf("number = %q", sms.Number)
+	}
+
+	if !contains(Backends(), "test-sms") || !contains(Backends(), "discord") {
+		t.Fatalf("Backends() = %v", Backends())
+	}
+	if _, err := newBackend(DestinationConfig{Name: "x", Type: "pigeon"}); err == nil {
+		t.Fatal("unknown type accepted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registered twice")
+		}
+	}()
+	Register("test-sms", decodedBackend[testBackend]())
+}
+
+func TestRegistryDecodeError(t *testing.T) {
+	d := DestinationConfig{}
+	if err := yaml.Unmarshal([]byte("name: oncall\ntype: test-sms\nnumber: [1, 2]\n"), &d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newBackend(d); err == nil {
+		t.Fatal("invalid settings accepted")
+	}
+}
+
+func TestBackendSettings(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"discord", "type: discord\nwebhook: https://discord.com/api/webhooks/xxx", true},
+		{"discord threads", "type: discord\nthreads_webhook: https://discord.com/api/webhooks/xxx", true},
+		{"discord without webhook", "type: discord\nkinds: [jailed]", false},
+		{"misspelled key", "type: discord\nwebhok: https://discord.com/api/webhooks/xxx", false},
+		{"telegram", "type: telegram\ntoken: \"123:xxx\"\nchat_ids: [\"-100\"]", true},
+		{"telegram without chat", "type: telegram\ntoken: \"123:xxx\"", false},
+		{"email", "type: email\nhost: smtp:587\nfrom: a@example.com\nto: [b@example.com]", true},
+		{"email without to", "type: email\nhost: smtp:587\nfrom: a@example.com", false},
+		{"exec", "type: exec\ncommand: [/bin/true]\ntimeout: 5s", true},
+		{"exec without command", "type: exec\ntimeout: 5s", false},
+		{"alertmanager", "type: alertmanager\nurl: http://alertmanager:9093\nalert_timeout: 1h", true},
+		{"gotify without token", "type: gotify\nurl: https://gotify.example.com", false},
+	}
+	for _, tt := range tests {
+		d := DestinationConfig{}
+		if err := yaml.Unmarshal([]byte("name: test\n"+tt.yaml), &d); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, err := newBackend(d); (err == nil) != tt.valid {
+			t.Errorf("%s: newBackend() = %v", tt.name, err)
+		}
+	}
+}
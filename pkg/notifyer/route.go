@@ -0,0 +1,99 @@
+package notifyer
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Route send the events it match to Destinations, an empty matcher
+// (Chains, Kinds or Severities) match every event.
+type Route struct {
+	Chains       []string    `yaml:"chains"`
+	Kinds        []EventKind `yaml:"kinds"`
+	Severities   []Severity  `yaml:"severities"`
+	Destinations []string    `yaml:"destinations"`
+}
+
+// Match return true when the event match every matcher of the route
+func (r Route) Match(e Event) bool {
+	if len(r.Chains) > 0 && !contains(r.Chains, e.Chain) {
+		return false
+	}
+	if len(r.Kinds) > 0 && !contains(r.Kinds, e.Kind) {
+		return false
+	}
+	if len(r.Severities) > 0 && !contains(r.Severities, e.Severity) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// route return the destinations of every route matching the event, and the
+// destinations named in no route nor escalation, each destination is
+// returned once. The caller filter them with their own matchers.
+func (c Client) route(e Event) []destination {
+	if len(c.cfg.Routes) == 0 {
+		return c.destinations
+	}
+
+	ret := make([]destination, 0, len(c.destinations))
+	for _, r := range c.cfg.Routes {
+		if !r.Match(e) {
+			continue
+		}
+		for _, name := range r.Destinations {
+			d, ok := c.destination(name)
+			if !ok || containsDestination(ret, name) {
+				continue
+			}
+			ret = append(ret, d)
+		}
+	}
+	for _, d := range c.destinations {
+		if !c.referenced(d.name) && !containsDestination(ret, d.name) {
+			ret = append(ret, d)
+		}
+	}
+
+	if len(ret) == 0 {
+		logrus.WithFields(logrus.Fields{
+			"chain":    e.Chain,
+			"kind":     e.Kind,
+			"severity": e.Severity,
+		}).Warn("no route match the event, notification dropped")
+	}
+	return ret
+}
+
+// referenced return true when a route or an escalation send to the
+// destination name
+func (c Client) referenced(name string) bool {
+	for _, r := range c.cfg.Routes {
+		if contains(r.Destinations, name) {
+			return true
+		}
+	}
+	for _, esc := range c.cfg.Escalations {
+		if contains(esc.Destinations, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDestination(list []destination, name string) bool {
+	for _, d := range list {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
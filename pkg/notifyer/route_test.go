@@ -0,0 +1,47 @@
+package notifyer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientRoute(t *testing.T) {
+	routed, filtered, unfiltered, escalated := &recorder{}, &recorder{}, &recorder{}, &recorder{}
+	c, err := NewClient(Config{
+		Destinations: []DestinationConfig{
+			{Name: "routed", Backend: routed},
+			{Name: "filtered", Backend: filtered, Kinds: []EventKind{KindDelegation}},
+			{Name: "unfiltered", Backend: unfiltered},
+			{Name: "escalated", Backend: escalated},
+		},
+		Routes: []Route{
+			{Kinds: []EventKind{KindJailed}, Destinations: []string{"routed"}},
+		},
+		Escalations: []Escalation{
+			{Route: Route{Destinations: []string{"escalated"}}, After: time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Alert(AlertMsg{Event: Event{Chain: "juno", Kind: KindJailed}})
+	c.Delegation(DelegationMsg{Event: Event{Chain: "juno", Kind: KindDelegation}})
+
+	tests := []struct {
+		name string
+		rec  *recorder
+		want []string
+	}{
+		{"routed", routed, []string{"alert:jailed"}},
+		{"filtered", filtered, []string{"delegation:delegation"}},
+		{"unfiltered", unfiltered, []string{"alert:jailed", "delegation:delegation"}},
+		{"escalated", escalated, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.rec.list(); !equal(got, tt.want) {
+			t.Errorf("%s received %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
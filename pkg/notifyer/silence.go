@@ -0,0 +1,352 @@
+package notifyer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const silenceCheckInterval = 10 * time.Second
+
+var silencesBucket = []byte("silences")
+
+// Silence mute the notifications matching Chains and Kinds between StartsAt
+// and EndsAt, empty Chains or Kinds match everything. Muted notifications
+// are logged and summarised when the silence ends.
+type Silence struct {
+	ID       string      `json:"id" yaml:"id"`
+	Chains   []string    `json:"chains,omitempty" yaml:"chains"`
+	Kinds    []EventKind `json:"kinds,omitempty" yaml:"kinds"`
+	StartsAt time.Time   `json:"starts_at" yaml:"starts_at"`
+	EndsAt   time.Time   `json:"ends_at" yaml:"ends_at"`
+	Comment  string      `json:"comment,omitempty" yaml:"comment"`
+}
+
+// Active return true when the silence is in effect at t
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Match return true when the silence mute e, regardless of time
+func (s Silence) Match(e Event) bool {
+	if len(s.Chains) > 0 && !contains(s.Chains, e.Chain) {
+		return false
+	}
+	if len(s.Kinds) > 0 && !contains(s.Kinds, e.Kind) {
+		return false
+	}
+	return true
+}
+
+func (s Silence) validate() error {
+	if s.EndsAt.IsZero() {
+		return errors.New("silence: ends_at is required")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return errors.New("silence: ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// silenceState track what a silence muted
+type silenceState struct {
+	Silence
+
+	static    bool
+	muted     map[EventKind]int
+	summaried bool
+}
+
+// silencer hold static and runtime silences, runtime silences are
+// persisted in the database when enabled.
+type silencer struct {
+	db   *bolt.DB
+	info func(msg InfoMsg)
+
+	mu       sync.Mutex
+	silences map[string]*silenceState
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSilencer(db *bolt.DB, static []Silence, info func(msg InfoMsg)) (*silencer, error) {
+	s := &silencer{
+		db:       db,
+		info:     info,
+		silences: map[string]*silenceState{},
+		stop:     make(chan struct{}),
+	}
+
+	now := time.Now()
+	for i, silence := range static {
+		if silence.ID == "" {
+			silence.ID = fmt.Sprintf("static-%d", i)
+		}
+		if err := silence.validate(); err != nil {
+			return nil, errors.Annotatef(err, "silence %s", silence.ID)
+		}
+		s.silences[silence.ID] = &silenceState{
+			Silence:   silence,
+			static:    true,
+			muted:     map[EventKind]int{},
+			summaried: !now.Before(silence.EndsAt),
+		}
+	}
+
+	if db != nil {
+		err := db.View(func(tx *bolt.Tx) error {
+			return tx.Bucket(silencesBucket).ForEach(func(k, v []byte) error {
+				silence := Silence{}
+				if err := json.Unmarshal(v, &silence); err != nil {
+					logrus.WithError(err).Error("invalid silence in database")
+					return nil
+				}
+				s.silences[silence.ID] = &silenceState{
+					Silence: silence,
+					muted:   map[EventKind]int{},
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *silencer) Close() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// silenced return the silence muting e, if any, and count e as muted
+func (s *silencer) silenced(e Event) (Silence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.match(e)
+	if state == nil {
+		return Silence{}, false
+	}
+	state.muted[e.Kind] += 1
+	return state.Silence, true
+}
+
+// active return true when a silence mute e
+func (s *silencer) active(e Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.match(e) != nil
+}
+
+// match return the active silence muting e, must be called with the lock held
+func (s *silencer) match(e Event) *silenceState {
+	if e.Kind == KindSilenceEnded {
+		return nil
+	}
+
+	now := time.Now()
+	for _, state := range s.silences {
+		if state.Active(now) && state.Match(e) {
+			return state
+		}
+	}
+	return nil
+}
+
+func (s *silencer) add(silence Silence) (Silence, error) {
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if err := silence.validate(); err != nil {
+		return Silence{}, errors.Trace(err)
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return Silence{}, errors.Trace(err)
+	}
+	silence.ID = hex.EncodeToString(id)
+
+	if err := s.save(silence); err != nil {
+		return Silence{}, errors.Trace(err)
+	}
+
+	s.mu.Lock()
+	s.silences[silence.ID] = &silenceState{
+		Silence: silence,
+		muted:   map[EventKind]int{},
+	}
+	s.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"id":        silence.ID,
+		"chains":    silence.Chains,
+		"kinds":     silence.Kinds,
+		"starts_at": silence.StartsAt.Format(time.RFC3339),
+		"ends_at":   silence.EndsAt.Format(time.RFC3339),
+	}).Info("silence created")
+	return silence, nil
+}
+
+// expire end the silence now, its summary is sent on the next check
+func (s *silencer) expire(id string) error {
+	s.mu.Lock()
+	state, ok := s.silences[id]
+	if !ok || state.summaried {
+		s.mu.Unlock()
+		return errors.NotFoundf("silence %s", id)
+	}
+	now := time.Now()
+	if state.StartsAt.After(now) {
+		state.StartsAt = now
+	}
+	state.EndsAt = now
+	silence := state.Silence
+	static := state.static
+	s.mu.Unlock()
+
+	if static {
+		return nil
+	}
+	return errors.Trace(s.save(silence))
+}
+
+// list return the silences not ended yet, sorted by start time
+func (s *silencer) list() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := make([]Silence, 0, len(s.silences))
+	for _, state := range s.silences {
+		if !state.summaried {
+			ret = append(ret, state.Silence)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].StartsAt.Before(ret[j].StartsAt)
+	})
+	return ret
+}
+
+func (s *silencer) save(silence Silence) error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(silence)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(silencesBucket).Put([]byte(silence.ID), data)
+	})
+}
+
+func (s *silencer) delete(id string) {
+	if s.db == nil {
+		return
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(silencesBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		logrus.WithError(err).Error("failed to delete silence")
+	}
+}
+
+func (s *silencer) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(silenceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.summarise()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// summarise send the summary of every ended silence
+func (s *silencer) summarise() {
+	now := time.Now()
+
+	s.mu.Lock()
+	ended := make([]*silenceState, 0)
+	for id, state := range s.silences {
+		if state.summaried || now.Before(state.EndsAt) {
+			continue
+		}
+		state.summaried = true
+		ended = append(ended, state)
+		if !state.static {
+			delete(s.silences, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, state := range ended {
+		if !state.static {
+			s.delete(state.ID)
+		}
+		s.info(state.summary())
+	}
+}
+
+// summary return the info message sent when the silence ends
+func (state silenceState) summary() InfoMsg {
+	kinds := make([]string, 0, len(state.muted))
+	total := 0
+	for kind, n := range state.muted {
+		kinds = append(kinds, fmt.Sprintf("- %s: %d", kind, n))
+		total += n
+	}
+	sort.Strings(kinds)
+
+	title := "Silence " + state.ID + " ended"
+	if state.Comment != "" {
+		title += ": " + state.Comment
+	}
+
+	msg := fmt.Sprintf("%d notification(s) muted from %s to %s",
+		total, state.StartsAt.UTC().Format(time.RFC822), state.EndsAt.UTC().Format(time.RFC822))
+	if len(kinds) > 0 {
+		msg += "\n" + strings.Join(kinds, "\n")
+	}
+
+	e := Event{
+		Kind: KindSilenceEnded,
+		Fields: map[string]string{
+			"silence": state.ID,
+			"muted":   fmt.Sprint(total),
+		},
+	}
+	if len(state.Chains) == 1 {
+		e.Chain = state.Chains[0]
+	}
+
+	return InfoMsg{
+		Event: e,
+		Title: title,
+		Msg:   msg,
+	}
+}
@@ -0,0 +1,247 @@
+package notifyer
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSilenceMatch(t *testing.T) {
+	start := time.Date(2024, 5, 2, 14, 0, 0, 0, time.UTC)
+	s := Silence{
+		Chains:   []string{"juno"},
+		Kinds:    []EventKind{KindMissedBlocks, KindInactive},
+		StartsAt: start,
+		EndsAt:   start.Add(2 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		e      Event
+		t      time.Time
+		match  bool
+		active bool
+	}{
+		{"match", Event{Chain: "juno", Kind: KindMissedBlocks}, start, true, true},
+		{"other kind", Event{Chain: "juno", Kind: KindJailed}, start, false, true},
+		{"other chain", Event{Chain: "osmosis", Kind: KindInactive}, start, false, true},
+		{"before", Event{Chain: "juno", Kind: KindMissedBlocks}, start.Add(-time.Second), true, false},
+		{"last second", Event{Chain: "juno", Kind: KindMissedBlocks}, start.Add(2*time.Hour - time.Second), true, true},
+		{"at the end", Event{Chain: "juno", Kind: KindMissedBlocks}, start.Add(2 * time.Hour), true, false},
+	}
+	for _, tt := range tests {
+		if got := s.Match(tt.e); got != tt.match {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.match)
+		}
+		if got := s.Active(tt.t); got != tt.active {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.active)
+		}
+	}
+
+	if !(Silence{}).Match(Event{Chain: "osmosis", Kind: KindJailed}) {
+		t.Error("empty silence must match every event")
+	}
+}
+
+func TestSilenceValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		s     Silence
+		valid bool
+	}{
+		{"valid", Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}, true},
+		{"no end", Silence{StartsAt: now}, false},
+		{"ends before start", Silence{StartsAt: now, EndsAt: now.Add(-time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.s.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: validate() = %v", tt.name, err)
+		}
+	}
+}
+
+// infos record the info messages sent by a silencer
+type infos struct {
+	mu   sync.Mutex
+	msgs []InfoMsg
+}
+
+func (i *infos) info(msg InfoMsg) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.msgs = append(i.msgs, msg)
+}
+
+func (i *infos) list() []InfoMsg {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]InfoMsg(nil), i.msgs...)
+}
+
+func TestSilencerSummary(t *testing.T) {
+	out := &infos{}
+	s, err := newSilencer(nil, nil, out.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	silence, err := s.add(Silence{
+		Chains:  []string{"juno"},
+		EndsAt:  time.Now().Add(time.Hour),
+		Comment: "upgrade",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.list()) != 1 {
+		t.Fatalf("list() = %v", s.list())
+	}
+
+	for _, e := range []Event{
+		{Chain: "juno", Kind: KindMissedBlocks},
+		{Chain: "juno", Kind: KindMissedBlocks},
+		{Chain: "juno", Kind: KindInactive},
+	} {
+		if _, ok := s.silenced(e); !ok {
+			t.Fatalf("%s not silenced", e.Key())
+		}
+	}
+	if _, ok := s.silenced(Event{Chain: "osmosis", Kind: KindJailed}); ok {
+		t.Fatal("osmosis silenced")
+	}
+	// active does not count the event
+	if !s.active(Event{Chain: "juno", Kind: KindJailed}) {
+		t.Fatal("juno not silenced")
+	}
+	if s.active(Event{Chain: "juno", Kind: KindSilenceEnded}) {
+		t.Fatal("summaries must never be silenced")
+	}
+
+	if err := s.expire(silence.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.summarise()
+
+	msgs := out.list()
+	if len(msgs) != 1 {
+		t.Fatalf("%d summaries sent, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.Title != "Silence "+silence.ID+" ended: upgrade" || msg.Chain != "juno" || msg.Kind != KindSilenceEnded {
+		t.Fatalf("unexpected summary: %+v", msg)
+	}
+	if !strings.HasPrefix(msg.Msg, "3 notification(s) muted") ||
+		!strings.HasSuffix(msg.Msg, "\n- inactive: 1\n- missed-blocks: 2") {
+		t.Fatalf("unexpected summary: %q", msg.Msg)
+	}
+	if msg.Fields["muted"] != "3" {
+		t.Fatalf("muted = %s", msg.Fields["muted"])
+	}
+
+	// summarised once, and not listed anymore
+	s.summarise()
+	if len(out.list()) != 1 || len(s.list()) != 0 {
+		t.Fatal("silence summarised twice or still listed")
+	}
+	if err := s.expire(silence.ID); err == nil {
+		t.Fatal("expired an ended silence")
+	}
+}
+
+func TestSilencerPersistence(t *testing.T) {
+	db := testDB(t)
+
+	s, err := newSilencer(db, []Silence{{EndsAt: time.Now().Add(time.Hour)}}, func(InfoMsg) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	silence, err := s.add(Silence{Kinds: []EventKind{KindJailed}, EndsAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = newSilencer(db, []Silence{{EndsAt: time.Now().Add(time.Hour)}}, func(InfoMsg) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ids := []string{}
+	for _, silence := range s.list() {
+		ids = append(ids, silence.ID)
+	}
+	if len(ids) != 2 || !contains(ids, "static-0") || !contains(ids, silence.ID) {
+		t.Fatalf("silences after restart: %v", ids)
+	}
+}
+
+// recorder is a Backend recording the messages it receive
+type recorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *recorder) record(typ msgType, e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, string(typ)+":"+string(e.Kind))
+	return nil
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.msgs...)
+}
+
+func (r *recorder) Alert(msg AlertMsg) error {
+	return r.record(msgAlert, msg.Event)
+}
+
+func (r *recorder) Recover(msg RecoverMsg) error {
+	return r.record(msgRecover, msg.Event)
+}
+
+func (r *recorder) Delegation(msg DelegationMsg) error {
+	return r.record(msgDelegation, msg.Event)
+}
+
+func (r *recorder) UnDelegation(msg UnDelegationMsg) error {
+	return r.record(msgUnDelegation, msg.Event)
+}
+
+func (r *recorder) Info(msg InfoMsg) error {
+	return r.record(msgInfo, msg.Event)
+}
+
+func TestClientSilenceRecovery(t *testing.T) {
+	rec := &recorder{}
+	c, err := NewClient(Config{
+		Destinations: []DestinationConfig{{Name: "rec", Backend: rec}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// alerted before the silence, the recovery close the incident
+	c.Alert(AlertMsg{Event: Event{Chain: "juno", Kind: KindJailed}})
+	if _, err := c.AddSilence(Silence{Chains: []string{"juno"}, EndsAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	c.Alert(AlertMsg{Event: Event{Chain: "juno", Kind: KindJailed}})
+	c.Recover(RecoverMsg{Event: Event{Chain: "juno", Kind: KindJailed}})
+
+	// alerted during the silence, the recovery is silenced too
+	c.Alert(AlertMsg{Event: Event{Chain: "juno", Kind: KindMissedBlocks}})
+	c.Recover(RecoverMsg{Event: Event{Chain: "juno", Kind: KindMissedBlocks}})
+
+	want := []string{"alert:jailed", "recover:jailed"}
+	if got := rec.list(); !equal(got, want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package notifyer
+
+import (
+	"github.com/juju/errors"
+)
+
+// SlackClient is complient with the Service interface
+//
+// Messages are sent through a Slack incoming webhook using Block Kit
+type SlackClient struct {
+	Webhook string `yaml:"webhook"`
+}
+
+func (c SlackClient) validate() error {
+	if c.Webhook == "" {
+		return errors.New("slack: webhook is required")
+	}
+	return nil
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (c SlackClient) send(content string, e Event) error {
+	blocks := []slackBlock{
+		{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: content},
+		},
+	}
+
+	// a section support at most 10 fields
+	fields := make([]slackText, 0, 10)
+	for _, d := range e.details() {
+		if len(fields) == 10 {
+			break
+		}
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*" + d.Label + "*\n" + d.Value})
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, slackBlock{Type: "section", Fields: fields})
+	}
+
+	blocks = append(blocks, slackBlock{
+		Type: "context",
+		Elements: []slackText{
+			{Type: "mrkdwn", Text: "cosmos-notifyer"},
+		},
+	})
+
+	message := slackMessage{
+		Text:   content,
+		Blocks: blocks,
+	}
+
+	if err := postJSON(c.Webhook, message, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c SlackClient) Alert(msg AlertMsg) error {
+	return c.send(":rotating_light: "+msg.Msg, msg.Event)
+}
+
+func (c SlackClient) Recover(msg RecoverMsg) error {
+	return c.send(":ok_hand: "+msg.Msg, msg.Event)
+}
+
+func (c SlackClient) Delegation(msg DelegationMsg) error {
+	return c.send(":money_mouth_face: "+msg.Msg, msg.Event)
+}
+
+func (c SlackClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(":money_with_wings: "+msg.Msg, msg.Event)
+}
+
+func (c SlackClient) Info(msg InfoMsg) error {
+	return c.send(":information_source: *"+msg.Title+"*\n"+msg.Msg, msg.Event)
+}
@@ -0,0 +1,119 @@
+package notifyer
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+)
+
+// TeamsClient is complient with the Service interface
+//
+// Notifications are sent as Adaptive Cards to a Microsoft Teams incoming
+// webhook (or workflow webhook), coloured by type with a fact set.
+type TeamsClient struct {
+	Webhook string `yaml:"webhook"`
+}
+
+func (c TeamsClient) validate() error {
+	if c.Webhook == "" {
+		return errors.New("teams: webhook is required")
+	}
+	return nil
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// send post an adaptive card, color is one of the adaptive card colors
+// (Attention, Good, Accent, Warning...)
+func (c TeamsClient) send(color string, title string, text string, facts []teamsFact) error {
+	body := []teamsElement{
+		{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+	}
+	if text != "" {
+		body = append(body, teamsElement{Type: "TextBlock", Text: text, Wrap: true})
+	}
+	if len(facts) > 0 {
+		body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+	}
+
+	message := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+
+	if err := postJSON(c.Webhook, message, nil); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// teamsFacts build the fact set of the event followed by extra facts
+func teamsFacts(e Event, extra ...teamsFact) []teamsFact {
+	facts := make([]teamsFact, 0, len(extra)+6)
+	for _, d := range e.details() {
+		facts = append(facts, teamsFact{Title: d.Label, Value: d.Value})
+	}
+	return append(facts, extra...)
+}
+
+func (c TeamsClient) Alert(msg AlertMsg) error {
+	return c.send("Attention", "🚨 Alert", msg.Msg, teamsFacts(msg.Event))
+}
+
+func (c TeamsClient) Recover(msg RecoverMsg) error {
+	return c.send("Good", "👌 Recovered", msg.Msg, teamsFacts(msg.Event))
+}
+
+func (c TeamsClient) Delegation(msg DelegationMsg) error {
+	return c.send("Accent", "🤑 New delegation", msg.Msg, teamsFacts(msg.Event,
+		teamsFact{Title: "Amount", Value: fmt.Sprintf("%v %s", msg.Amount, msg.Token)},
+	))
+}
+
+func (c TeamsClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send("Warning", "💸 Lost delegation", msg.Msg, teamsFacts(msg.Event,
+		teamsFact{Title: "Amount", Value: fmt.Sprintf("%v %s", msg.Amount, msg.Token)},
+	))
+}
+
+func (c TeamsClient) Info(msg InfoMsg) error {
+	return c.send("Default", "ℹ️ "+msg.Title, msg.Msg, teamsFacts(msg.Event))
+}
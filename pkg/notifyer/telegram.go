@@ -0,0 +1,106 @@
+package notifyer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// TelegramClient is complient with the Service interface
+//
+// Every message is sent to each chat of ChatIDs through the Bot API
+type TelegramClient struct {
+	Token   string   `yaml:"token"`
+	ChatIDs []string `yaml:"chat_ids"`
+	// APIURL default to https://api.telegram.org, e.g. for a local Bot API server
+	APIURL string `yaml:"api_url"`
+}
+
+func (c TelegramClient) validate() error {
+	if c.Token == "" || len(c.ChatIDs) == 0 {
+		return errors.New("telegram: token and chat_ids are required")
+	}
+	return nil
+}
+
+func (c TelegramClient) apiURL() string {
+	if c.APIURL == "" {
+		return telegramAPI
+	}
+	return c.APIURL
+}
+
+type telegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// escapeMarkdownV2 escape every reserved character of the MarkdownV2 style
+func escapeMarkdownV2(s string) string {
+	return telegramEscaper.Replace(s)
+}
+
+func (c TelegramClient) targets() []string {
+	return c.ChatIDs
+}
+
+func (c TelegramClient) target(chatID string) Backend {
+	c.ChatIDs = []string{chatID}
+	return c
+}
+
+// telegramError return err without the request URL, which hold the bot token
+func telegramError(err error) error {
+	if uerr, ok := errors.Cause(err).(*url.Error); ok {
+		return errors.Errorf("%s: %v", uerr.Op, uerr.Err)
+	}
+	return err
+}
+
+func (c TelegramClient) send(emoji string, content string) error {
+	var errs error
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", c.apiURL(), c.Token)
+	for _, chatID := range c.ChatIDs {
+		message := telegramMessage{
+			ChatID:    chatID,
+			Text:      emoji + " " + escapeMarkdownV2(content),
+			ParseMode: "MarkdownV2",
+		}
+		if err := postJSON(endpoint, message, nil); err != nil {
+			errs = errors.Wrap(errs, errors.Annotatef(telegramError(err), "telegram chat %s", chatID))
+		}
+	}
+	return errs
+}
+
+func (c TelegramClient) Alert(msg AlertMsg) error {
+	return c.send("🚨", msg.Msg)
+}
+
+func (c TelegramClient) Recover(msg RecoverMsg) error {
+	return c.send("👌", msg.Msg)
+}
+
+func (c TelegramClient) Delegation(msg DelegationMsg) error {
+	return c.send("🤑", msg.Msg)
+}
+
+func (c TelegramClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send("💸", msg.Msg)
+}
+
+func (c TelegramClient) Info(msg InfoMsg) error {
+	return c.send("ℹ️", msg.Title+"\n"+msg.Msg)
+}
@@ -0,0 +1,277 @@
+package notifyer
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// TemplatesDefault is the Templates key applying to every destination
+const TemplatesDefault = "default"
+
+// Templates render the Msg of every message, keyed by destination name
+// (or TemplatesDefault) then by "<type>.<kind>" or "<type>", e.g.
+//
+//	default:
+//	  alert.jailed: "{{.Moniker}} is jailed on {{.Chain}}"
+//	  delegation: "+{{humanize .Amount}} {{.Token}}"
+//
+// Types are alert, recover, delegation, undelegation and info. The most specific
+// template is used: destination then default, "<type>.<kind>" then "<type>".
+type Templates map[string]map[string]string
+
+// TemplateData is the data available inside templates
+type TemplateData struct {
+	Event
+
+	Type string
+	// Msg is the message set by the caller, if any
+	Msg string
+
+	Amount float64
+	Token  string
+
+	// Title of info messages
+	Title string
+}
+
+// builtinTemplates render the message set by the caller, e.g. the text of an
+// inbound alert, and describe the event otherwise
+var builtinTemplates = map[string]string{
+	"alert":                 `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{with .Moniker}}{{.}} {{end}}{{.Kind}}{{end}}`,
+	"recover":               `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{with .Moniker}}{{.}} {{end}}{{.Kind}} recovered{{end}}`,
+	"alert.rpc-down":        `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] No valid RPC ({{.Fields.rpcs}}){{end}}`,
+	"recover.rpc-down":      `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] RPCs are back up !{{end}}`,
+	"alert.jailed":          `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{.Moniker}} is jailed{{end}}`,
+	"recover.jailed":        `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{.Moniker}} is un-jailed{{end}}`,
+	"alert.inactive":        `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] validator: {{.Moniker}} is not in the active set{{end}}`,
+	"recover.inactive":      `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] validator: {{.Moniker}} is back in the active set{{end}}`,
+	"alert.missed-blocks":   `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{.Moniker}} Not signing blocs... {{.Fields.missed_blocks}} blocks{{end}}`,
+	"recover.missed-blocks": `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{.Moniker}} Signing block again, missed blocks: {{.Fields.missed_blocks}}{{end}}`,
+	"alert.flapping":        `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{with .Moniker}}{{.}} {{end}}{{.Fields.condition}} is flapping, {{.Fields.state_changes}} state changes in {{.Fields.window}}{{end}}`,
+	"recover.flapping":      `{{if .Msg}}{{.Msg}}{{else}}[{{.Chain}}] {{with .Moniker}}{{.}} {{end}}{{.Fields.condition}} stopped flapping{{end}}`,
+	"delegation":            `{{with .Chain}}[{{.}}] {{end}}new delegation of {{.Amount}} {{.Token}}`,
+	"undelegation":          `{{with .Chain}}[{{.}}] {{end}}lost delegation of {{.Amount}} {{.Token}}`,
+	"info":                  `{{.Msg}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	"humanize": humanize,
+	"duration": humanizeDuration,
+	"since": func(t time.Time) string {
+		return humanizeDuration(time.Since(t))
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"default": func(def string, v string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// renderer hold the parsed templates, keyed by destination then by template
+// key, builtin templates are stored under the "" destination
+type renderer struct {
+	templates map[string]map[string]*template.Template
+}
+
+func newRenderer(cfg Templates) (*renderer, error) {
+	r := &renderer{
+		templates: map[string]map[string]*template.Template{},
+	}
+
+	parse := func(dest string, key string, text string) error {
+		t, err := template.New(dest + ":" + key).
+			Funcs(templateFuncs).
+			Option("missingkey=zero").
+			Parse(text)
+		if err != nil {
+			return errors.Annotatef(err, "template %s %s", dest, key)
+		}
+		if r.templates[dest] == nil {
+			r.templates[dest] = map[string]*template.Template{}
+		}
+		r.templates[dest][key] = t
+		return nil
+	}
+
+	for key, text := range builtinTemplates {
+		if err := parse("", key, text); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for dest, templates := range cfg {
+		for key, text := range templates {
+			if err := parse(dest, key, text); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// lookup return the most specific template for dest
+func (r renderer) lookup(dest string, typ msgType, kind EventKind) *template.Template {
+	keys := []string{string(typ) + "." + string(kind), string(typ)}
+	for _, d := range []string{dest, TemplatesDefault, ""} {
+		for _, key := range keys {
+			if t, ok := r.templates[d][key]; ok {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+func (r renderer) render(dest string, typ msgType, data TemplateData) string {
+	data.Type = string(typ)
+
+	t := r.lookup(dest, typ, data.Kind)
+	if t == nil {
+		return data.Msg
+	}
+
+	buf := bytes.Buffer{}
+	if err := t.Execute(&buf, data); err != nil {
+		logrus.WithError(err).WithField("template", t.Name()).Error("failed to render template")
+
+		// fallback on the builtin template
+		buf.Reset()
+		if t = r.lookup("", typ, data.Kind); t == nil || t.Execute(&buf, data) != nil {
+			return data.Msg
+		}
+	}
+	return buf.String()
+}
+
+// renderMsg return a copy of msg with Msg rendered for dest
+func (r renderer) renderMsg(dest string, typ msgType, msg interface{}) interface{} {
+	switch m := msg.(type) {
+	case AlertMsg:
+		m.Msg = r.render(dest, typ, TemplateData{Event: m.Event, Msg: m.Msg})
+		return m
+	case RecoverMsg:
+		m.Msg = r.render(dest, typ, TemplateData{Event: m.Event, Msg: m.Msg})
+		return m
+	case DelegationMsg:
+		m.Msg = r.render(dest, typ, TemplateData{Event: m.Event, Msg: m.Msg, Amount: m.Amount, Token: m.Token})
+		return m
+	case UnDelegationMsg:
+		m.Msg = r.render(dest, typ, TemplateData{Event: m.Event, Msg: m.Msg, Amount: m.Amount, Token: m.Token})
+		return m
+	case InfoMsg:
+		m.Msg = r.render(dest, typ, TemplateData{Event: m.Event, Msg: m.Msg, Title: m.Title})
+		return m
+	}
+	return msg
+}
+
+// humanize format a number with thousands separators and at most 2 decimals,
+// e.g. 1234567.891 -> 1,234,567.89
+func humanize(v interface{}) string {
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case uint64:
+		f = float64(n)
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(n, 64); err != nil {
+			return n
+		}
+	default:
+		return fmt.Sprint(v)
+	}
+
+	s := strconv.FormatFloat(math.Abs(f), 'f', 2, 64)
+	s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+
+	intPart, decPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, decPart = s[:i], s[i:]
+	}
+
+	b := strings.Builder{}
+	if f < 0 {
+		b.WriteByte('-')
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(decPart)
+	return b.String()
+}
+
+// humanizeDuration format a duration (or a number of seconds) with its two
+// most significant units, e.g. 2d 3h or 5m 12s
+func humanizeDuration(v interface{}) string {
+	var d time.Duration
+	switch n := v.(type) {
+	case time.Duration:
+		d = n
+	case int:
+		d = time.Duration(n) * time.Second
+	case int64:
+		d = time.Duration(n) * time.Second
+	case float64:
+		d = time.Duration(n * float64(time.Second))
+	case string:
+		var err error
+		if d, err = time.ParseDuration(n); err != nil {
+			secs, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return n
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+	default:
+		return fmt.Sprint(v)
+	}
+
+	if d < time.Second {
+		return "0s"
+	}
+
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	parts := make([]string, 0, 2)
+	for _, u := range units {
+		if len(parts) == 2 {
+			break
+		}
+		if d >= u.d {
+			parts = append(parts, fmt.Sprintf("%d%s", d/u.d, u.name))
+			d %= u.d
+		} else if len(parts) > 0 {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,128 @@
+package notifyer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRendererLookup(t *testing.T) {
+	r, err := newRenderer(Templates{
+		"slack": {
+			"alert.jailed": "slack jailed",
+			"alert":        "slack alert",
+		},
+		TemplatesDefault: {
+			"alert.inactive": "default inactive",
+			"recover":        "default recover",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := func(kind EventKind) Event {
+		return Event{Chain: "juno", Moniker: "nysa", Kind: kind}
+	}
+	tests := []struct {
+		name string
+		dest string
+		typ  msgType
+		e    Event
+		want string
+	}{
+		{"destination type.kind", "slack", msgAlert, e(KindJailed), "slack jailed"},
+		{"destination type over default type.kind", "slack", msgAlert, e(KindInactive), "slack alert"},
+		{"default type.kind", "discord", msgAlert, e(KindInactive), "default inactive"},
+		{"default type over builtin type.kind", "slack", msgRecover, e(KindJailed), "default recover"},
+		{"builtin type.kind", "discord", msgAlert, e(KindJailed), "[juno] nysa is jailed"},
+		{"builtin type", "discord", msgAlert, e(KindExternal), "[juno] nysa external"},
+	}
+	for _, tt := range tests {
+		if got := r.render(tt.dest, tt.typ, TemplateData{Event: tt.e}); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRendererFallback(t *testing.T) {
+	r, err := newRenderer(Templates{
+		TemplatesDefault: {
+			"alert":          "{{call .Msg}}",
+			"delegation":     "{{call .Token}}",
+			"info":           "{{.Title}}: {{.Msg}}",
+			"alert.rpc-down": "{{.Fields.rpcs | upper}} down since {{.Fields.since | duration}}",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		typ  msgType
+		data TemplateData
+		want string
+	}{
+		{"failed template use the builtin", msgAlert, TemplateData{Event: Event{Chain: "juno", Kind: KindExternal}, Msg: "lagging"}, "lagging"},
+		{"failed template use the builtin type", msgDelegation, TemplateData{Event: Event{Chain: "juno"}, Amount: 5, Token: "JUNO"}, "[juno] new delegation of 5 JUNO"},
+		{"title", msgInfo, TemplateData{Title: "digest", Msg: "all good"}, "digest: all good"},
+		{"funcs", msgAlert, TemplateData{Event: Event{Kind: KindRPCDown, Fields: map[string]string{"rpcs": "a,b", "since": "3700"}}}, "A,B down since 1h 1m"},
+	}
+	for _, tt := range tests {
+		if got := r.render("slack", tt.typ, tt.data); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := newRenderer(Templates{"slack": {"alert": "{{.Chain"}}); err == nil {
+		t.Error("invalid template accepted")
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{0.0, "0"},
+		{1234567.891, "1,234,567.89"},
+		{-1234.5, "-1,234.5"},
+		{999, "999"},
+		{int64(1000), "1,000"},
+		{uint64(12345678), "12,345,678"},
+		{float32(2.5), "2.5"},
+		{"1000000", "1,000,000"},
+		{"abc", "abc"},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := humanize(tt.v); got != tt.want {
+			t.Errorf("humanize(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{time.Duration(0), "0s"},
+		{500 * time.Millisecond, "0s"},
+		{45 * time.Second, "45s"},
+		{5*time.Minute + 12*time.Second, "5m 12s"},
+		{2*time.Hour + 30*time.Second, "2h"},
+		{51 * time.Hour, "2d 3h"},
+		{90, "1m 30s"},
+		{int64(3600), "1h"},
+		{1.5, "1s"},
+		{"90m", "1h 30m"},
+		{"120", "2m"},
+		{"soon", "soon"},
+	}
+	for _, tt := range tests {
+		if got := humanizeDuration(tt.v); got != tt.want {
+			t.Errorf("humanizeDuration(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
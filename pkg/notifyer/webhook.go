@@ -0,0 +1,165 @@
+package notifyer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// WebhookPayloadVersion is bumped on every breaking change of WebhookPayload
+	WebhookPayloadVersion = 2
+
+	// WebhookSignatureHeader hold the hex encoded HMAC-SHA256 of the request body
+	WebhookSignatureHeader = "X-Cosmos-Notifyer-Signature"
+)
+
+// WebhookClient is complient with the Service interface
+//
+// Every notification is POSTed as a WebhookPayload to each of URLs.
+// When Secret is set, the body is signed with HMAC-SHA256 into the
+// WebhookSignatureHeader header as "sha256=<hex>".
+type WebhookClient struct {
+	URLs   []string `yaml:"urls"`
+	Secret string   `yaml:"secret"`
+}
+
+func (c WebhookClient) validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("webhook: urls is required")
+	}
+	return nil
+}
+
+// WebhookPayload is the JSON body sent by WebhookClient
+type WebhookPayload struct {
+	Version   int               `json:"version"`
+	Type      string            `json:"type"`
+	Kind      string            `json:"kind"`
+	Key       string            `json:"key"`
+	Severity  string            `json:"severity"`
+	Chain     string            `json:"chain"`
+	Validator string            `json:"validator,omitempty"`
+	Moniker   string            `json:"moniker,omitempty"`
+	Height    int64             `json:"height,omitempty"`
+	Amount    float64           `json:"amount,omitempty"`
+	Token     string            `json:"token,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Msg       string            `json:"msg,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Explorer  string            `json:"explorer_url,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// sign return the signature header value of body
+func (c WebhookClient) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c WebhookClient) targets() []string {
+	return c.URLs
+}
+
+func (c WebhookClient) target(url string) Backend {
+	c.URLs = []string{url}
+	return c
+}
+
+func (c WebhookClient) send(p WebhookPayload) error {
+	p.Version = WebhookPayloadVersion
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	headers := map[string]string{}
+	if c.Secret != "" {
+		headers[WebhookSignatureHeader] = c.sign(body)
+	}
+
+	var errs error
+	for _, url := range c.URLs {
+		if err := post(url, "application/json", body, headers); err != nil {
+			errs = errors.Wrap(errs, errors.Annotatef(err, "webhook %s", url))
+		}
+	}
+	return errs
+}
+
+func (c WebhookClient) Alert(msg AlertMsg) error {
+	return c.send(alertPayload(msg))
+}
+
+func (c WebhookClient) Recover(msg RecoverMsg) error {
+	return c.send(recoverPayload(msg))
+}
+
+func (c WebhookClient) Delegation(msg DelegationMsg) error {
+	return c.send(delegationPayload(msg))
+}
+
+func (c WebhookClient) UnDelegation(msg UnDelegationMsg) error {
+	return c.send(unDelegationPayload(msg))
+}
+
+func (c WebhookClient) Info(msg InfoMsg) error {
+	return c.send(infoPayload(msg))
+}
+
+func eventPayload(typ string, e Event) WebhookPayload {
+	return WebhookPayload{
+		Type:      typ,
+		Kind:      string(e.Kind),
+		Key:       e.Key(),
+		Severity:  string(e.Severity),
+		Chain:     e.Chain,
+		Validator: e.Validator,
+		Moniker:   e.Moniker,
+		Height:    e.Height,
+		Fields:    e.Fields,
+		Explorer:  e.ExplorerURL,
+		Timestamp: e.Time,
+	}
+}
+
+func alertPayload(msg AlertMsg) WebhookPayload {
+	p := eventPayload("alert", msg.Event)
+	p.Msg = msg.Msg
+	return p
+}
+
+func recoverPayload(msg RecoverMsg) WebhookPayload {
+	p := eventPayload("recover", msg.Event)
+	p.Msg = msg.Msg
+	return p
+}
+
+func delegationPayload(msg DelegationMsg) WebhookPayload {
+	p := eventPayload("delegation", msg.Event)
+	p.Amount = msg.Amount
+	p.Token = msg.Token
+	p.Msg = msg.Msg
+	return p
+}
+
+func unDelegationPayload(msg UnDelegationMsg) WebhookPayload {
+	p := eventPayload("undelegation", msg.Event)
+	p.Amount = msg.Amount
+	p.Token = msg.Token
+	p.Msg = msg.Msg
+	return p
+}
+
+func infoPayload(msg InfoMsg) WebhookPayload {
+	p := eventPayload("info", msg.Event)
+	p.Title = msg.Title
+	p.Msg = msg.Msg
+	return p
+}